@@ -4,4 +4,7 @@ go 1.19
 
 require github.com/gorilla/mux v1.8.1
 
-require github.com/google/uuid v1.6.0 // indirect
+require (
+	github.com/google/uuid v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
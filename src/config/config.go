@@ -0,0 +1,318 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the runtime configuration of the API server.
+//
+// Values are resolved with the following precedence (highest first):
+// command line flags, HOTELS_* environment variables, the JSON config file
+// and finally the built-in defaults.
+type Config struct {
+	Addr            string        `json:"addr"`
+	DataPath        string        `json:"dataPath"`
+	PublicDir       string        `json:"publicDir"`
+	ReadTimeout     Duration      `json:"readTimeout"`
+	WriteTimeout    Duration      `json:"writeTimeout"`
+	IdleTimeout     Duration      `json:"idleTimeout"`
+	ShutdownTimeout Duration      `json:"shutdownTimeout"`
+	CORS            CORSConfig    `json:"cors"`
+	LogFormat       string        `json:"logFormat"` // "text" or "json"
+	LenientSearch   bool          `json:"lenientSearch"`
+	OpenAPI         OpenAPIConfig `json:"openapi"`
+
+	// RoomTypes are the room types of hotels by hotel ID (config file only).
+	// Other hotels get room types generated from their property category.
+	RoomTypes map[string][]RoomTypeConfig `json:"roomTypes,omitempty"`
+
+	// SameDayTurnover sets by hotel ID whether a stay can start on the day another one ends in the same room
+	// (config file only). Hotels allow it unless set to false.
+	SameDayTurnover map[string]bool `json:"sameDayTurnover,omitempty"`
+}
+
+// RoomTypeConfig configures a room type of a hotel
+type RoomTypeConfig struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Count int    `json:"count"` // number of rooms, at least 1
+}
+
+// OpenAPIConfig holds the settings of the OpenAPI validation middleware
+type OpenAPIConfig struct {
+	SpecPath   string `json:"specPath"`
+	Validation string `json:"validation"` // "off", "request", "dev" or "dev-fail"
+}
+
+// CORSConfig holds the CORS settings applied to every response
+type CORSConfig struct {
+	AllowedOrigins []string `json:"allowedOrigins"`
+	AllowedMethods []string `json:"allowedMethods"`
+	AllowedHeaders []string `json:"allowedHeaders"`
+}
+
+// Duration is a time.Duration that is encoded in JSON as a string such as "15s"
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string such as "15s" or "1m30s"
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("duration must be a string such as \"15s\"")
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Addr:            ":8080",
+		DataPath:        "mock-data/hotels-data.json",
+		PublicDir:       "public",
+		ReadTimeout:     Duration(15 * time.Second),
+		WriteTimeout:    Duration(15 * time.Second),
+		IdleTimeout:     Duration(60 * time.Second),
+		ShutdownTimeout: Duration(15 * time.Second),
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+		},
+		LogFormat: "text",
+		OpenAPI: OpenAPIConfig{
+			SpecPath:   "api-spec/openapi.yml",
+			Validation: "off",
+		},
+	}
+}
+
+// Load resolves the configuration from the given command line arguments,
+// the process environment and the optional config file.
+// printConfig reports whether --print-config was requested.
+func Load(args []string) (cfg *Config, printConfig bool, err error) {
+	fs := flag.NewFlagSet("hotels-api", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	defaults := Default()
+	configPath := fs.String("config", "", "path to a JSON config file (env HOTELS_CONFIG)")
+	fs.BoolVar(&printConfig, "print-config", false, "print the effective configuration and exit")
+	addr := fs.String("addr", defaults.Addr, "listen address (env HOTELS_ADDR)")
+	dataPath := fs.String("data", defaults.DataPath, "path to the hotels JSON data file (env HOTELS_DATA_PATH)")
+	publicDir := fs.String("public-dir", defaults.PublicDir, "directory served as static assets (env HOTELS_PUBLIC_DIR)")
+	readTimeout := fs.Duration("read-timeout", time.Duration(defaults.ReadTimeout), "HTTP read timeout (env HOTELS_READ_TIMEOUT)")
+	writeTimeout := fs.Duration("write-timeout", time.Duration(defaults.WriteTimeout), "HTTP write timeout (env HOTELS_WRITE_TIMEOUT)")
+	idleTimeout := fs.Duration("idle-timeout", time.Duration(defaults.IdleTimeout), "HTTP idle timeout (env HOTELS_IDLE_TIMEOUT)")
+	shutdownTimeout := fs.Duration("shutdown-timeout", time.Duration(defaults.ShutdownTimeout), "graceful shutdown deadline (env HOTELS_SHUTDOWN_TIMEOUT)")
+	corsOrigins := fs.String("cors-origins", strings.Join(defaults.CORS.AllowedOrigins, ","), "comma separated allowed CORS origins (env HOTELS_CORS_ORIGINS)")
+	corsMethods := fs.String("cors-methods", strings.Join(defaults.CORS.AllowedMethods, ","), "comma separated allowed CORS methods (env HOTELS_CORS_METHODS)")
+	corsHeaders := fs.String("cors-headers", strings.Join(defaults.CORS.AllowedHeaders, ","), "comma separated allowed CORS headers (env HOTELS_CORS_HEADERS)")
+	logFormat := fs.String("log-format", defaults.LogFormat, "log format: text or json (env HOTELS_LOG_FORMAT)")
+	openAPISpec := fs.String("openapi-spec", defaults.OpenAPI.SpecPath, "path to the OpenAPI spec used for validation (env HOTELS_OPENAPI_SPEC)")
+	openAPIValidation := fs.String("openapi-validation", defaults.OpenAPI.Validation, "OpenAPI validation: off, request, dev or dev-fail (env HOTELS_OPENAPI_VALIDATION)")
+	lenientSearch := fs.Bool("lenient-search", defaults.LenientSearch, "ignore invalid hotel search parameters instead of rejecting them (env HOTELS_LENIENT_SEARCH)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, false, fmt.Errorf("error parsing flags: %w", err)
+	}
+
+	// Start from the defaults
+	cfg = defaults
+
+	// Apply the config file, if any
+	if *configPath == "" {
+		*configPath = os.Getenv("HOTELS_CONFIG")
+	}
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Apply environment variables
+	if err := cfg.loadEnv(); err != nil {
+		return nil, false, err
+	}
+
+	// Apply flags that were explicitly set on the command line
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "data":
+			cfg.DataPath = *dataPath
+		case "public-dir":
+			cfg.PublicDir = *publicDir
+		case "read-timeout":
+			cfg.ReadTimeout = Duration(*readTimeout)
+		case "write-timeout":
+			cfg.WriteTimeout = Duration(*writeTimeout)
+		case "idle-timeout":
+			cfg.IdleTimeout = Duration(*idleTimeout)
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout = Duration(*shutdownTimeout)
+		case "cors-origins":
+			cfg.CORS.AllowedOrigins = splitList(*corsOrigins)
+		case "cors-methods":
+			cfg.CORS.AllowedMethods = splitList(*corsMethods)
+		case "cors-headers":
+			cfg.CORS.AllowedHeaders = splitList(*corsHeaders)
+		case "log-format":
+			cfg.LogFormat = *logFormat
+		case "lenient-search":
+			cfg.LenientSearch = *lenientSearch
+		case "openapi-spec":
+			cfg.OpenAPI.SpecPath = *openAPISpec
+		case "openapi-validation":
+			cfg.OpenAPI.Validation = *openAPIValidation
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	return cfg, printConfig, nil
+}
+
+// Validate checks that the configuration values are usable
+func (c *Config) Validate() error {
+	if c.Addr == "" {
+		return errors.New("invalid config: addr must not be empty")
+	}
+	if c.DataPath == "" {
+		return errors.New("invalid config: dataPath must not be empty")
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("invalid config: logFormat must be \"text\" or \"json\", got %q", c.LogFormat)
+	}
+	switch c.OpenAPI.Validation {
+	case "off", "request", "dev", "dev-fail":
+	default:
+		return fmt.Errorf("invalid config: openapi.validation must be \"off\", \"request\", \"dev\" or \"dev-fail\", got %q", c.OpenAPI.Validation)
+	}
+	if c.OpenAPI.Validation != "off" && c.OpenAPI.SpecPath == "" {
+		return errors.New("invalid config: openapi.specPath must not be empty when validation is enabled")
+	}
+	for name, d := range map[string]Duration{
+		"readTimeout":     c.ReadTimeout,
+		"writeTimeout":    c.WriteTimeout,
+		"idleTimeout":     c.IdleTimeout,
+		"shutdownTimeout": c.ShutdownTimeout,
+	} {
+		if d < 0 {
+			return fmt.Errorf("invalid config: %s must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// Print writes the configuration as indented JSON
+func (c *Config) Print(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// loadFile overlays the values found in a JSON config file
+func (c *Config) loadFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// loadEnv overlays the values found in HOTELS_* environment variables
+func (c *Config) loadEnv() error {
+	stringVars := map[string]*string{
+		"HOTELS_ADDR":               &c.Addr,
+		"HOTELS_DATA_PATH":          &c.DataPath,
+		"HOTELS_PUBLIC_DIR":         &c.PublicDir,
+		"HOTELS_LOG_FORMAT":         &c.LogFormat,
+		"HOTELS_OPENAPI_SPEC":       &c.OpenAPI.SpecPath,
+		"HOTELS_OPENAPI_VALIDATION": &c.OpenAPI.Validation,
+	}
+	for name, target := range stringVars {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = value
+		}
+	}
+
+	durations := map[string]*Duration{
+		"HOTELS_READ_TIMEOUT":     &c.ReadTimeout,
+		"HOTELS_WRITE_TIMEOUT":    &c.WriteTimeout,
+		"HOTELS_IDLE_TIMEOUT":     &c.IdleTimeout,
+		"HOTELS_SHUTDOWN_TIMEOUT": &c.ShutdownTimeout,
+	}
+	for name, target := range durations {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = Duration(parsed)
+		}
+	}
+
+	bools := map[string]*bool{
+		"HOTELS_LENIENT_SEARCH": &c.LenientSearch,
+	}
+	for name, target := range bools {
+		if value, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	lists := map[string]*[]string{
+		"HOTELS_CORS_ORIGINS": &c.CORS.AllowedOrigins,
+		"HOTELS_CORS_METHODS": &c.CORS.AllowedMethods,
+		"HOTELS_CORS_HEADERS": &c.CORS.AllowedHeaders,
+	}
+	for name, target := range lists {
+		if value, ok := os.LookupEnv(name); ok {
+			*target = splitList(value)
+		}
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated list, trimming blanks and dropping empty entries
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets the HOTELS_* environment variables for the duration of the test
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, variable := range os.Environ() {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if !strings.HasPrefix(name, "HOTELS_") {
+			continue
+		}
+		value := os.Getenv(name)
+		os.Unsetenv(name)
+		t.Cleanup(func() { os.Setenv(name, value) })
+	}
+}
+
+// writeConfig writes a config file in a temporary directory and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+	cfg, printConfig, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printConfig {
+		t.Error("printConfig = true, want false")
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load = %+v, want the defaults %+v", cfg, Default())
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `{
+		"addr": ":1001",
+		"dataPath": "file.json",
+		"publicDir": "file-public",
+		"readTimeout": "1s",
+		"logFormat": "json",
+		"cors": {"allowedOrigins": ["https://file.example"]}
+	}`)
+	t.Setenv("HOTELS_CONFIG", path)
+	t.Setenv("HOTELS_ADDR", ":2002")
+	t.Setenv("HOTELS_DATA_PATH", "env.json")
+	t.Setenv("HOTELS_READ_TIMEOUT", "2s")
+	t.Setenv("HOTELS_LENIENT_SEARCH", "true")
+	t.Setenv("HOTELS_CORS_ORIGINS", "https://a.example, ,https://b.example")
+
+	cfg, _, err := Load([]string{"--addr", ":3003", "--read-timeout", "3s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"addr from the flag over env and file", cfg.Addr, ":3003"},
+		{"readTimeout from the flag over env and file", cfg.ReadTimeout, Duration(3 * time.Second)},
+		{"dataPath from env over the file", cfg.DataPath, "env.json"},
+		{"lenientSearch from env over the default", cfg.LenientSearch, true},
+		{"CORS origins from env over the file", cfg.CORS.AllowedOrigins, []string{"https://a.example", "https://b.example"}},
+		{"publicDir from the file", cfg.PublicDir, "file-public"},
+		{"logFormat from the file", cfg.LogFormat, "json"},
+		{"writeTimeout from the defaults", cfg.WriteTimeout, Default().WriteTimeout},
+		{"CORS methods from the defaults", cfg.CORS.AllowedMethods, Default().CORS.AllowedMethods},
+	}
+	for _, tc := range tests {
+		if !reflect.DeepEqual(tc.got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestLoadConfigFlagOverridesEnv(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("HOTELS_CONFIG", writeConfig(t, `{"addr": ":1001"}`))
+	cfg, _, err := Load([]string{"--config", writeConfig(t, `{"addr": ":2002"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":2002" {
+		t.Errorf("Addr = %q, want the one of the --config file", cfg.Addr)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string // config file content, none when empty
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "unknown key", file: `{"adr": ":1001"}`, want: `unknown field "adr"`},
+		{name: "unknown nested key", file: `{"cors": {"origins": []}}`, want: `unknown field "origins"`},
+		{name: "invalid JSON", file: `{"addr": }`, want: "error parsing config file"},
+		{name: "duration not a string", file: `{"readTimeout": 15}`, want: "duration must be a string"},
+		{name: "invalid duration", file: `{"readTimeout": "soon"}`, want: `invalid duration "soon"`},
+		{name: "missing file", args: []string{"--config", "missing.json"}, want: "error reading config file"},
+		{name: "invalid env duration", env: map[string]string{"HOTELS_IDLE_TIMEOUT": "x"}, want: "invalid HOTELS_IDLE_TIMEOUT"},
+		{name: "invalid env bool", env: map[string]string{"HOTELS_LENIENT_SEARCH": "maybe"}, want: "invalid HOTELS_LENIENT_SEARCH"},
+		{name: "unknown flag", args: []string{"--port", "80"}, want: "error parsing flags"},
+		{name: "invalid log format", args: []string{"--log-format", "xml"}, want: `logFormat must be "text" or "json", got "xml"`},
+		{name: "invalid validation mode", args: []string{"--openapi-validation", "on"}, want: "openapi.validation must be"},
+		{name: "negative timeout", args: []string{"--read-timeout", "-1s"}, want: "readTimeout must not be negative"},
+		{name: "empty addr", env: map[string]string{"HOTELS_ADDR": ""}, want: "addr must not be empty"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clearEnv(t)
+			if tc.file != "" {
+				t.Setenv("HOTELS_CONFIG", writeConfig(t, tc.file))
+			}
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+			_, _, err := Load(tc.args)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	clearEnv(t)
+	cfg, printConfig, err := Load([]string{"--print-config", "--addr", ":3003", "--cors-headers", "X-Api-Key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !printConfig {
+		t.Error("printConfig = false, want true")
+	}
+
+	// The printed configuration is a valid config file giving the same configuration
+	var printed bytes.Buffer
+	if err := cfg.Print(&printed); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(printed.String(), `"readTimeout": "15s"`) {
+		t.Errorf("durations are not printed as strings:\n%s", printed.String())
+	}
+	reloaded, _, err := Load([]string{"--config", writeConfig(t, printed.String())})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded, cfg) {
+		t.Errorf("reloaded config = %+v, want %+v", reloaded, cfg)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
+)
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// sendErrorResponse sends an RFC 7807 problem+json response with the provided status code and detail message
+func sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	sendProblem(w, models.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: message,
+	})
+}
+
+// sendValidationError sends a 400 problem+json response listing every offending field
+func sendValidationError(w http.ResponseWriter, err *services.ValidationError) {
+	sendProblem(w, models.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusBadRequest),
+		Status: http.StatusBadRequest,
+		Detail: "One or more fields are invalid",
+		Errors: err.Fields,
+	})
+}
+
+// sendServiceError maps an error returned by the services to a problem+json response.
+// This is the single place deciding which status code each domain error gets.
+func sendServiceError(w http.ResponseWriter, err error) {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		sendValidationError(w, validationErr)
+	case errors.Is(err, services.ErrHotelNotFound),
+		errors.Is(err, services.ErrReservationNotFound):
+		sendErrorResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrDateConflict),
+		errors.Is(err, services.ErrHotelHasReservations),
+		errors.Is(err, services.ErrReservationExists),
+		errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, services.ErrReservationClosed):
+		sendErrorResponse(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("Unexpected error: %v", err)
+		sendErrorResponse(w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// sendProblem writes a problem details body
+func sendProblem(w http.ResponseWriter, problem models.Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// NotFoundHandler returns a handler for requests no route matched.
+// If the path is served for other methods it answers 405 with an Allow header
+// (gorilla/mux loses that information for subrouters), otherwise 404.
+func NotFoundHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allowed := allowedMethods(router, r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			sendErrorResponse(w, http.StatusMethodNotAllowed, "Method "+r.Method+" is not allowed on "+r.URL.Path)
+			return
+		}
+		sendErrorResponse(w, http.StatusNotFound, "No route matches "+r.URL.Path)
+	})
+}
+
+// MethodNotAllowedHandler returns a handler answering unsupported methods with a 405 problem+json response
+func MethodNotAllowedHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allowed := allowedMethods(router, r); len(allowed) > 0 {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+		}
+		sendErrorResponse(w, http.StatusMethodNotAllowed, "Method "+r.Method+" is not allowed on "+r.URL.Path)
+	})
+}
+
+// allowedMethods returns the methods for which a route matches the request's path
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"} {
+		if method == r.Method {
+			continue
+		}
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
+)
+
+// fieldSet is a sparse fieldset: the fields to keep in a resource, by JSON name.
+// An empty sub-set keeps the whole field; otherwise only the listed nested fields are kept.
+type fieldSet map[string]fieldSet
+
+// Fields that sparse fieldsets may select, by resource
+var (
+	hotelFields             = fieldsOf(reflect.TypeOf(models.Hotel{}))
+	hotelResultFields       = fieldsOf(reflect.TypeOf(models.HotelResult{}))
+	hotelReservationsFields = fieldsOf(reflect.TypeOf(models.HotelWithReservations{}))
+	reservationFields       = fieldsOf(reflect.TypeOf(models.Reservation{}))
+)
+
+// includeReservations is the include= value embedding a hotel's reservations
+const includeReservations = "reservations"
+
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// fieldsOf lists every field of the JSON representation of a type, including nested fields.
+// Values with their own JSON encoding (e.g. time.Time) are leaves.
+func fieldsOf(t reflect.Type) fieldSet {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t.Implements(jsonMarshaler) {
+		return nil
+	}
+
+	fields := fieldSet{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-" || !field.IsExported():
+			continue
+		case field.Anonymous && name == "":
+			// Embedded structs are flattened
+			for embedded, sub := range fieldsOf(field.Type) {
+				fields[embedded] = sub
+			}
+			continue
+		case name == "":
+			name = field.Name
+		}
+		fields[name] = fieldsOf(field.Type)
+	}
+	return fields
+}
+
+// parseFieldsParam reads fields=name,parent.child,... against the fields of a resource.
+// It returns nil when every field is wanted.
+func parseFieldsParam(p *queryParser, allowed fieldSet) fieldSet {
+	raw := p.string("fields")
+	if raw == "" {
+		return nil
+	}
+
+	selected := fieldSet{}
+	for _, path := range strings.Split(raw, ",") {
+		if !selected.add(strings.Split(path, "."), allowed) {
+			p.fail("fields", fmt.Sprintf("unknown field %q", path))
+			return nil
+		}
+	}
+	return selected
+}
+
+// add selects a field path, checking every step exists. Selecting a field selects all of its nested fields.
+func (set fieldSet) add(path []string, allowed fieldSet) bool {
+	sub, ok := allowed[path[0]]
+	if !ok {
+		return false
+	}
+	if len(path) == 1 {
+		set[path[0]] = fieldSet{}
+		return true
+	}
+
+	nested, selected := set[path[0]]
+	if selected && len(nested) == 0 {
+		// The whole field is already selected, but the path must still exist
+		return fieldSet{}.add(path[1:], sub)
+	}
+	if !selected {
+		nested = fieldSet{}
+		set[path[0]] = nested
+	}
+	return nested.add(path[1:], sub)
+}
+
+// project keeps the selected fields of a decoded JSON object, or of every object of an array
+func (set fieldSet) project(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		projected := make(map[string]interface{}, len(set))
+		for name, sub := range set {
+			if field, ok := value[name]; ok {
+				if len(sub) == 0 {
+					projected[name] = field
+				} else {
+					projected[name] = sub.project(field)
+				}
+			}
+		}
+		return projected
+	case []interface{}:
+		projected := make([]interface{}, len(value))
+		for i, item := range value {
+			projected[i] = set.project(item)
+		}
+		return projected
+	}
+	return value
+}
+
+// selectFields returns data with only the selected fields of its resources. With a collection name,
+// the resources are the items of that property of data; otherwise data is the resource itself.
+// A nil set returns data untouched.
+func selectFields(data interface{}, set fieldSet, collection string) (interface{}, error) {
+	if set == nil {
+		return data, nil
+	}
+
+	// Work on the JSON representation, keeping numbers as they are
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	if object, ok := generic.(map[string]interface{}); ok && collection != "" {
+		object[collection] = set.project(object[collection])
+		return object, nil
+	}
+	return set.project(generic), nil
+}
+
+// parseResourceFields reads the fields parameter of a request for a single resource or a resource listing
+func parseResourceFields(r *http.Request, allowed fieldSet) (fieldSet, *services.ValidationError) {
+	p := newQueryParser(r.URL.Query(), false)
+	fields := parseFieldsParam(p, allowed)
+	return fields, p.result()
+}
+
+// parseHotelParams reads the fields and include parameters of a request for a single hotel.
+// include=reservations embeds the hotel's reservations, whose fields can then be selected too.
+func parseHotelParams(r *http.Request) (fieldSet, bool, *services.ValidationError) {
+	p := newQueryParser(r.URL.Query(), false)
+
+	withReservations := false
+	if include := p.string("include"); include != "" {
+		for _, name := range strings.Split(include, ",") {
+			if name != includeReservations {
+				p.fail("include", fmt.Sprintf("unknown resource %q, must be %s", name, includeReservations))
+				continue
+			}
+			withReservations = true
+		}
+	}
+
+	allowed := hotelFields
+	if withReservations {
+		allowed = hotelReservationsFields
+	}
+	fields := parseFieldsParam(p, allowed)
+
+	return fields, withReservations, p.result()
+}
+
+// sendFieldsResponse sends a JSON response keeping only the selected fields (see selectFields)
+func sendFieldsResponse(w http.ResponseWriter, status int, data interface{}, fields fieldSet, collection string) {
+	selected, err := selectFields(data, fields, collection)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(selected)
+}
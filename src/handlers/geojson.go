@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// geoJSONContentType is the media type of GeoJSON documents
+const geoJSONContentType = "application/geo+json"
+
+// geoJSONSuffix selects the GeoJSON variant of a hotel route, e.g. /api/hotels.geojson
+const geoJSONSuffix = ".geojson"
+
+// wantsGeoJSON reports whether a request asks for GeoJSON, either with the .geojson
+// route suffix or with an Accept header preferring application/geo+json over application/json
+func wantsGeoJSON(r *http.Request) bool {
+	if strings.HasSuffix(r.URL.Path, geoJSONSuffix) {
+		return true
+	}
+
+	geoJSON, plainJSON := 0.0, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		switch mediaType {
+		case geoJSONContentType:
+			geoJSON = quality
+		case "application/json":
+			plainJSON = quality
+		}
+	}
+	return geoJSON > 0 && geoJSON >= plainJSON
+}
+
+// sendGeoJSONResponse sends hotels as a GeoJSON FeatureCollection: every hotel is a Point
+// feature at its location, with the rest of its selected fields as properties
+func sendGeoJSONResponse(w http.ResponseWriter, hotels []models.HotelResult, fields fieldSet) {
+	features := make([]models.Feature, 0, len(hotels))
+	for _, hotel := range hotels {
+		feature, err := hotelFeature(hotel.Hotel, hotel, fields)
+		if err != nil {
+			sendServiceError(w, err)
+			return
+		}
+		features = append(features, feature)
+	}
+	sendFeatureCollection(w, features)
+}
+
+// sendFeatureCollection sends features as a GeoJSON FeatureCollection
+func sendFeatureCollection(w http.ResponseWriter, features []models.Feature) {
+	collection := models.FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}
+
+	w.Header().Set("Content-Type", geoJSONContentType)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// hotelFeature converts a hotel to a GeoJSON Point feature. The properties are the selected fields
+// of resource, the representation of the hotel in the response.
+func hotelFeature(hotel models.Hotel, resource interface{}, fields fieldSet) (models.Feature, error) {
+	// Every field but the location becomes a property
+	selected, err := selectFields(resource, fields, "")
+	if err != nil {
+		return models.Feature{}, err
+	}
+	data, err := json.Marshal(selected)
+	if err != nil {
+		return models.Feature{}, err
+	}
+	var properties map[string]json.RawMessage
+	if err := json.Unmarshal(data, &properties); err != nil {
+		return models.Feature{}, err
+	}
+	delete(properties, "location")
+
+	return models.Feature{
+		Type: "Feature",
+		ID:   hotel.ID,
+		Geometry: models.Point{
+			Type:        "Point",
+			Coordinates: [2]float64{hotel.Location.Longitude, hotel.Location.Latitude},
+		},
+		Properties: properties,
+	}, nil
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -12,103 +14,291 @@ import (
 
 // HotelHandler handles HTTP requests for hotel data
 type HotelHandler struct {
-	Service *services.HotelService
+	Service            *services.HotelService
+	ReservationService *services.ReservationService
+
+	// LenientSearch makes hotel search ignore invalid and unknown query parameters instead of rejecting them
+	LenientSearch bool
 }
 
 // NewHotelHandler creates a new instance of HotelHandler
-func NewHotelHandler(service *services.HotelService) *HotelHandler {
+func NewHotelHandler(service *services.HotelService, reservationService *services.ReservationService) *HotelHandler {
 	return &HotelHandler{
-		Service: service,
+		Service:            service,
+		ReservationService: reservationService,
 	}
 }
 
 // GetHotels handles GET requests for searching hotels
 func (h *HotelHandler) GetHotels(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters for search filters
-	params := parseSearchParams(r)
+	params, options, problems := parseSearchParams(r, h.LenientSearch)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
 
 	// Search hotels based on parameters
-	hotels := h.Service.SearchHotels(params)
+	page := h.Service.SearchHotels(params)
+
+	// Describe the pagination in headers
+	links := pageLinks(r, page, params.Limit, params.Cursor != nil)
+	setPaginationHeaders(w, page, params.CountTotal, links)
+
+	// Return results, as GeoJSON if requested
+	w.Header().Add("Vary", "Accept")
+	if wantsGeoJSON(r) {
+		sendGeoJSONResponse(w, page.Hotels, options.fields)
+		return
+	}
+	response := models.HotelSearchResponse{Hotels: page.Hotels}
+	if options.meta {
+		response.PageMeta = pageMeta(r, page, params.Limit, links)
+	}
+	if options.facets != nil {
+		_, response.Facets = h.Service.FacetHotels(params, options.facets)
+	}
+	sendFieldsResponse(w, http.StatusOK, response, options.fields, "hotels")
+}
+
+// GetHotelClusters handles GET requests grouping the matching hotels into map clusters
+func (h *HotelHandler) GetHotelClusters(w http.ResponseWriter, r *http.Request) {
+	// Parse the filters and zoom level
+	params, zoom, problems := parseClusterParams(r, h.LenientSearch)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
+	// Group the matching hotels
+	clusters := h.Service.ClusterHotels(params, zoom)
+
+	// Return results
+	total := 0
+	for _, cluster := range clusters {
+		total += cluster.Count
+	}
+	sendJSONResponse(w, models.ClusterResponse{Zoom: zoom, Total: total, Clusters: clusters})
+}
+
+// GetHotelFacets handles GET requests counting the matching hotels by facet value
+func (h *HotelHandler) GetHotelFacets(w http.ResponseWriter, r *http.Request) {
+	// Parse the filters and facets
+	params, facets, problems := parseFacetParams(r, h.LenientSearch)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
+	// Count the matching hotels
+	total, counts := h.Service.FacetHotels(params, facets)
 
 	// Return results
-	sendJSONResponse(w, models.HotelResponse{Hotels: hotels})
+	sendJSONResponse(w, models.FacetResponse{Total: total, Facets: counts})
 }
 
-// GetHotelByID handles GET requests for a specific hotel by ID
+// GetAmenities handles GET requests for the amenity catalogue.
+// Labels are in the language given by lang, else negotiated from Accept-Language.
+func (h *HotelHandler) GetAmenities(w http.ResponseWriter, r *http.Request) {
+	// Pick the label language
+	language, problems := parseAmenityLanguage(r, h.LenientSearch)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
+	// Return the catalogue
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Set("Content-Language", language)
+	sendJSONResponse(w, models.AmenityResponse{Language: language, Amenities: models.AmenityCatalogue(language)})
+}
+
+// GetHotelSuggestions handles GET requests for autocomplete suggestions of a partial query
+func (h *HotelHandler) GetHotelSuggestions(w http.ResponseWriter, r *http.Request) {
+	// Parse the partial query and limit
+	query, limit, problems := parseSuggestParams(r, h.LenientSearch)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
+	// Return suggestions
+	sendJSONResponse(w, models.SuggestionResponse{Suggestions: h.Service.Suggest(query, limit)})
+}
+
+// GetHotelByID handles GET requests for a specific hotel by ID.
+// include=reservations embeds the hotel's reservations in the response.
 func (h *HotelHandler) GetHotelByID(w http.ResponseWriter, r *http.Request) {
 	// Get ID from URL parameters
 	vars := mux.Vars(r)
 	id := vars["hotelId"]
 
+	// Parse the fields to return and the related resources to include
+	fields, withReservations, problems := parseHotelParams(r)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
 	// Find hotel by ID
 	hotel, err := h.Service.GetHotelByID(id)
 	if err != nil {
-		sendErrorResponse(w, http.StatusNotFound, "Hotel not found")
+		sendServiceError(w, err)
 		return
 	}
 
-	// Return the hotel
-	sendJSONResponse(w, hotel)
+	// Embed its reservations if requested
+	var resource interface{} = hotel
+	if withReservations {
+		reservations, err := h.ReservationService.GetReservationsByHotelID(id)
+		if err != nil {
+			sendServiceError(w, err)
+			return
+		}
+		resource = models.HotelWithReservations{Hotel: *hotel, Reservations: reservations}
+	}
+
+	// Return the hotel, as GeoJSON if requested
+	w.Header().Add("Vary", "Accept")
+	if wantsGeoJSON(r) {
+		feature, err := hotelFeature(*hotel, resource, fields)
+		if err != nil {
+			sendServiceError(w, err)
+			return
+		}
+		sendFeatureCollection(w, []models.Feature{feature})
+		return
+	}
+	sendFieldsResponse(w, http.StatusOK, resource, fields, "")
 }
 
-// parseSearchParams extracts search parameters from the HTTP request
-func parseSearchParams(r *http.Request) models.SearchParams {
-	query := r.URL.Query()
-	params := models.SearchParams{
-		Name:        query.Get("name"),
-		City:        query.Get("city"),
-		CountryCode: query.Get("countryCode"),
+// CreateHotel handles POST requests to create a new hotel
+func (h *HotelHandler) CreateHotel(w http.ResponseWriter, r *http.Request) {
+	// Parse the fields to return
+	fields, problems := parseResourceFields(r, hotelFields)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
 	}
 
-	// Parse numeric parameters with proper error handling
-	if minRate := query.Get("minRate"); minRate != "" {
-		if val, err := strconv.ParseFloat(minRate, 64); err == nil {
-			params.MinRate = val
-		}
+	// Parse request body
+	var hotel models.Hotel
+	if err := decodeJSONBody(r, &hotel); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
 	}
 
-	if maxRate := query.Get("maxRate"); maxRate != "" {
-		if val, err := strconv.ParseFloat(maxRate, 64); err == nil {
-			params.MaxRate = val
-		}
+	// Create the hotel
+	created, err := h.Service.CreateHotel(hotel)
+	if err != nil {
+		sendServiceError(w, err)
+		return
 	}
 
-	if minRating := query.Get("minRating"); minRating != "" {
-		if val, err := strconv.ParseFloat(minRating, 64); err == nil {
-			params.MinRating = val
-		}
+	// Return the created hotel
+	w.Header().Set("Location", "/api"+created.Metadata.Path)
+	sendFieldsResponse(w, http.StatusCreated, created, fields, "")
+}
+
+// ReplaceHotel handles PUT requests to replace an existing hotel
+func (h *HotelHandler) ReplaceHotel(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL parameters
+	vars := mux.Vars(r)
+	id := vars["hotelId"]
+
+	// Parse the fields to return
+	fields, problems := parseResourceFields(r, hotelFields)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
 	}
 
-	if maxRating := query.Get("maxRating"); maxRating != "" {
-		if val, err := strconv.ParseFloat(maxRating, 64); err == nil {
-			params.MaxRating = val
-		}
+	// Parse request body
+	var hotel models.Hotel
+	if err := decodeJSONBody(r, &hotel); err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
 	}
 
-	if amenityMask := query.Get("amenityMask"); amenityMask != "" {
-		if val, err := strconv.Atoi(amenityMask); err == nil {
-			params.AmenityMask = val
-		}
+	// Replace the hotel
+	updated, err := h.Service.ReplaceHotel(id, hotel)
+	if err != nil {
+		sendServiceError(w, err)
+		return
 	}
 
-	// Parse pagination parameters
-	limit := 20 // Default limit
-	if limitParam := query.Get("limit"); limitParam != "" {
-		if val, err := strconv.Atoi(limitParam); err == nil && val > 0 {
-			limit = val
-		}
+	// Return the updated hotel
+	sendFieldsResponse(w, http.StatusOK, updated, fields, "")
+}
+
+// PatchHotel handles PATCH requests applying a JSON merge patch to an existing hotel
+func (h *HotelHandler) PatchHotel(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL parameters
+	vars := mux.Vars(r)
+	id := vars["hotelId"]
+
+	// Parse the fields to return
+	fields, problems := parseResourceFields(r, hotelFields)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
 	}
-	params.Limit = limit
 
-	offset := 0 // Default offset
-	if offsetParam := query.Get("offset"); offsetParam != "" {
-		if val, err := strconv.Atoi(offsetParam); err == nil && val >= 0 {
-			offset = val
+	// Only JSON merge patches are supported
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			sendErrorResponse(w, http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+			return
 		}
 	}
-	params.Offset = offset
 
-	return params
+	// Read request body
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		sendErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Patch the hotel
+	updated, err := h.Service.PatchHotel(id, patch)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Return the updated hotel
+	sendFieldsResponse(w, http.StatusOK, updated, fields, "")
+}
+
+// DeleteHotel handles DELETE requests to remove a hotel.
+// Hotels with reservations are only deleted when cascade=true is given, which also deletes the reservations.
+func (h *HotelHandler) DeleteHotel(w http.ResponseWriter, r *http.Request) {
+	// Get ID from URL parameters
+	vars := mux.Vars(r)
+	id := vars["hotelId"]
+
+	cascade, err := strconv.ParseBool(r.URL.Query().Get("cascade"))
+	if err != nil && r.URL.Query().Get("cascade") != "" {
+		sendValidationError(w, &services.ValidationError{Fields: []models.FieldError{{Field: "cascade", Message: "must be true or false"}}})
+		return
+	}
+
+	// Delete the hotel
+	if err := h.ReservationService.DeleteHotel(id, cascade); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Return success with no content
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeJSONBody decodes the JSON request body into v, rejecting unknown fields
+func decodeJSONBody(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
 }
 
 // sendJSONResponse sends a JSON response with the provided data
@@ -117,13 +307,3 @@ func sendJSONResponse(w http.ResponseWriter, data interface{}) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(data)
 }
-
-// sendErrorResponse sends a JSON error response with the provided status code and message
-func sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(models.ErrorResponse{
-		Code:    statusCode,
-		Message: message,
-	})
-}
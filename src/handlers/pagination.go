@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
+)
+
+// pageParamNames are the query parameters selecting a page or its representation rather than the search results
+var pageParamNames = map[string]bool{"limit": true, "offset": true, "cursor": true, "meta": true, "fields": true}
+
+// cursorToken is the content of an opaque cursor: the edge of a page and the search it belongs to
+type cursorToken struct {
+	Search string `json:"f"` // fingerprint of the search parameters
+	models.SearchCursor
+}
+
+// searchFingerprint identifies the search parameters of a query, ignoring the page parameters,
+// so a cursor cannot be used with a different search
+func searchFingerprint(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		if !pageParamNames[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	hash := fnv.New32a()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s=%q;", name, query[name])
+	}
+	return strconv.FormatUint(uint64(hash.Sum32()), 36)
+}
+
+// encodeCursor builds the opaque cursor of a page of the given search
+func encodeCursor(cursor *models.SearchCursor, query url.Values) string {
+	data, _ := json.Marshal(cursorToken{Search: searchFingerprint(query), SearchCursor: *cursor})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor reads an opaque cursor, checking it belongs to the search of the query
+func decodeCursor(raw string, query url.Values) (*models.SearchCursor, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, false
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil || token.ID == "" || token.Search != searchFingerprint(query) {
+		return nil, false
+	}
+	if !validCursor(token.SearchCursor) {
+		return nil, false
+	}
+	return &token.SearchCursor, true
+}
+
+// validCursor reports whether the values of a cursor are ones a search could have produced.
+// Cursors come from clients: a corpus without documents, without text or with more hotels having
+// a term than hotels would turn relevance scores into NaN or infinities, which JSON cannot encode.
+func validCursor(c models.SearchCursor) bool {
+	if c.Seq < 0 || c.Score < 0 || c.Distance < 0 || c.LowRate < 0 || c.HighRate < 0 ||
+		c.HotelRating < 0 || c.TripAdvisorRating < 0 || c.Modified < 0 {
+		return false
+	}
+	if corpus := c.Corpus; corpus != nil {
+		if corpus.Documents <= 0 || corpus.AvgLength <= 0 {
+			return false
+		}
+		for _, documents := range corpus.Terms {
+			if documents < 0 || documents > corpus.Documents {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// pageLinks builds the URLs of the pages around a search page.
+// Requests paginated by cursor get cursor links, others get offset links, and a last link when the total was counted.
+func pageLinks(r *http.Request, page services.SearchPage, limit int, byCursor bool) map[string]string {
+	query := r.URL.Query()
+	link := func(set func(query url.Values)) string {
+		q := url.Values{}
+		for name, values := range query {
+			q[name] = values
+		}
+		q.Del("offset")
+		q.Del("cursor")
+		set(q)
+		return (&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).String()
+	}
+
+	links := map[string]string{"first": link(func(url.Values) {})}
+	if byCursor {
+		if page.Next != nil {
+			links["next"] = link(func(q url.Values) { q.Set("cursor", encodeCursor(page.Next, query)) })
+		}
+		if page.Prev != nil {
+			links["prev"] = link(func(q url.Values) { q.Set("cursor", encodeCursor(page.Prev, query)) })
+		}
+		return links
+	}
+
+	setOffset := func(offset int) func(url.Values) {
+		return func(q url.Values) {
+			if offset > 0 {
+				q.Set("offset", strconv.Itoa(offset))
+			}
+		}
+	}
+	if page.Next != nil {
+		links["next"] = link(setOffset(page.Offset + limit))
+	}
+	if page.Offset > 0 {
+		prev := page.Offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links["prev"] = link(setOffset(prev))
+	}
+	if page.Total > 0 {
+		links["last"] = link(setOffset((page.Total - 1) / limit * limit))
+	}
+	return links
+}
+
+// setPaginationHeaders sends RFC 8288 Link headers, and the total number of matches (X-Total-Count) when it was counted
+func setPaginationHeaders(w http.ResponseWriter, page services.SearchPage, counted bool, links map[string]string) {
+	if counted {
+		w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	}
+
+	var values []string
+	for _, rel := range []string{"first", "prev", "next", "last"} {
+		if target, ok := links[rel]; ok {
+			values = append(values, fmt.Sprintf("<%s>; rel=%q", target, rel))
+		}
+	}
+	w.Header().Set("Link", strings.Join(values, ", "))
+}
+
+// pageMeta builds the pagination metadata of a search response
+func pageMeta(r *http.Request, page services.SearchPage, limit int, links map[string]string) *models.PageMeta {
+	meta := &models.PageMeta{
+		Total:  page.Total,
+		Limit:  limit,
+		Offset: page.Offset,
+		Next:   links["next"],
+		Prev:   links["prev"],
+	}
+	if page.Next != nil {
+		meta.NextCursor = encodeCursor(page.Next, r.URL.Query())
+	}
+	if page.Prev != nil {
+		meta.PrevCursor = encodeCursor(page.Prev, r.URL.Query())
+	}
+	return meta
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	query := url.Values{"q": {"harbor"}}
+	corpus := func(documents int, avgLength float64, df int) *models.TextCorpus {
+		return &models.TextCorpus{Documents: documents, AvgLength: avgLength, Terms: map[string]int{"harbor": df}}
+	}
+
+	tests := []struct {
+		name   string
+		cursor models.SearchCursor
+		valid  bool
+	}{
+		{"relevance", models.SearchCursor{Seq: 3, ID: "a", Score: 1.5, Corpus: corpus(30, 12.5, 4)}, true},
+		{"term in every hotel", models.SearchCursor{ID: "a", Corpus: corpus(30, 12.5, 30)}, true},
+		{"no documents", models.SearchCursor{ID: "a", Corpus: corpus(0, 12.5, 0)}, false},
+		{"negative documents", models.SearchCursor{ID: "a", Corpus: corpus(-1, 12.5, 0)}, false},
+		{"zero average length", models.SearchCursor{ID: "a", Corpus: corpus(30, 0, 4)}, false},
+		{"negative average length", models.SearchCursor{ID: "a", Corpus: corpus(30, -2, 4)}, false},
+		{"term in more hotels than the corpus", models.SearchCursor{ID: "a", Corpus: corpus(30, 12.5, 31)}, false},
+		{"negative term count", models.SearchCursor{ID: "a", Corpus: corpus(30, 12.5, -1)}, false},
+		{"negative sequence", models.SearchCursor{Seq: -1, ID: "a"}, false},
+		{"negative score", models.SearchCursor{ID: "a", Score: -1}, false},
+		{"negative distance", models.SearchCursor{ID: "a", Distance: -1}, false},
+		{"negative rate", models.SearchCursor{ID: "a", LowRate: -1}, false},
+		{"no hotel ID", models.SearchCursor{Seq: 3}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, ok := decodeCursor(encodeCursor(&tc.cursor, query), query)
+			if ok != tc.valid {
+				t.Errorf("decodeCursor ok = %v, want %v", ok, tc.valid)
+			}
+		})
+	}
+
+	valid := encodeCursor(&models.SearchCursor{ID: "a"}, query)
+	if _, ok := decodeCursor(valid, url.Values{"q": {"garden"}}); ok {
+		t.Error("decodeCursor accepted the cursor of another search")
+	}
+	if _, ok := decodeCursor("not a cursor", query); ok {
+		t.Error("decodeCursor accepted a cursor that is not base64")
+	}
+	nan := base64.RawURLEncoding.EncodeToString([]byte(`{"f":"` + searchFingerprint(query) + `","id":"a","tc":{"n":30,"len":NaN}}`))
+	if _, ok := decodeCursor(nan, query); ok {
+		t.Error("decodeCursor accepted a NaN average length")
+	}
+}
+
+func TestGetHotelsRejectsInvalidCursors(t *testing.T) {
+	hotels := services.NewHotelService()
+	hotels.SetHotels([]models.Hotel{{ID: "0248058a-27e4-11e6-ace6-a9876eff01b3", Name: "Harbor Hotel", City: "Seattle"}})
+	handler := NewHotelHandler(hotels, services.NewReservationService(hotels))
+
+	query := url.Values{"q": {"harbor"}}
+	cursor := models.SearchCursor{ID: "a", Corpus: &models.TextCorpus{Documents: 1, AvgLength: 0, Terms: map[string]int{"harbor": 1}}}
+	query.Set("cursor", encodeCursor(&cursor, query))
+	rec := httptest.NewRecorder()
+	handler.GetHotels(rec, httptest.NewRequest("GET", "/api/hotels?"+query.Encode(), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+}
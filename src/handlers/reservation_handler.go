@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
@@ -21,21 +23,60 @@ func NewReservationHandler(service *services.ReservationService) *ReservationHan
 	}
 }
 
-// GetReservations handles GET requests for all reservations of a hotel
+// GetReservations handles GET requests for all reservations of a hotel.
+// The optional from and to query parameters (YYYY-MM-DD) restrict the listing to reservations holding a night
+// from the night of from to the night before to, and status (comma-separated) to reservations in those statuses.
 func (h *ReservationHandler) GetReservations(w http.ResponseWriter, r *http.Request) {
 	// Get hotelId from URL parameters
 	vars := mux.Vars(r)
 	hotelID := vars["hotelId"]
 
+	// Parse the fields to return
+	fields, problems := parseResourceFields(r, reservationFields)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
+	// Optionally restrict the listing to reservations holding a night of a date range
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	if (from == "") != (to == "") {
+		sendValidationError(w, &services.ValidationError{Fields: []models.FieldError{{Field: "from", Message: "from and to must be given together"}}})
+		return
+	}
+
+	// Optionally restrict the listing to some statuses
+	statuses, problems := parseStatusParam(query.Get("status"))
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
 	// Get reservations from service
-	reservations, err := h.Service.GetReservationsByHotelID(hotelID)
+	var reservations []models.Reservation
+	var err error
+	if from != "" {
+		reservations, err = h.Service.GetReservationsInRange(hotelID, from, to)
+	} else {
+		reservations, err = h.Service.GetReservationsByHotelID(hotelID)
+	}
 	if err != nil {
-		sendErrorResponse(w, http.StatusNotFound, err.Error())
+		sendServiceError(w, err)
 		return
 	}
+	if statuses != nil {
+		filtered := []models.Reservation{}
+		for _, reservation := range reservations {
+			if statuses[reservation.Status] {
+				filtered = append(filtered, reservation)
+			}
+		}
+		reservations = filtered
+	}
 
 	// Return results
-	sendJSONResponse(w, models.ReservationResponse{Reservations: reservations})
+	sendFieldsResponse(w, http.StatusOK, models.ReservationResponse{Reservations: reservations}, fields, "reservations")
 }
 
 // GetReservationByID handles GET requests for a specific reservation
@@ -45,15 +86,22 @@ func (h *ReservationHandler) GetReservationByID(w http.ResponseWriter, r *http.R
 	hotelID := vars["hotelId"]
 	reservationID := vars["reservationId"]
 
+	// Parse the fields to return
+	fields, problems := parseResourceFields(r, reservationFields)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
 	// Get the reservation
 	reservation, err := h.Service.GetReservationByID(hotelID, reservationID)
 	if err != nil {
-		sendErrorResponse(w, http.StatusNotFound, err.Error())
+		sendServiceError(w, err)
 		return
 	}
 
 	// Return the reservation
-	sendJSONResponse(w, reservation)
+	sendFieldsResponse(w, http.StatusOK, reservation, fields, "")
 }
 
 // CreateReservation handles POST requests to create a new reservation
@@ -62,6 +110,13 @@ func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	hotelID := vars["hotelId"]
 
+	// Parse the fields to return
+	fields, problems := parseResourceFields(r, reservationFields)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
 	// Parse request body
 	var req models.CreateReservationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -70,28 +125,20 @@ func (h *ReservationHandler) CreateReservation(w http.ResponseWriter, r *http.Re
 	}
 
 	// Validate required fields
-	if req.CustomerName == "" || req.StartDate == "" || req.EndDate == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "CustomerName, StartDate, and EndDate are required fields")
+	if err := validateRequiredReservationFields(req.CustomerName, req.StartDate, req.EndDate); err != nil {
+		sendValidationError(w, err)
 		return
 	}
 
 	// Create the reservation
 	reservation, err := h.Service.CreateReservation(hotelID, req)
 	if err != nil {
-		if err.Error() == "hotel not found" {
-			sendErrorResponse(w, http.StatusNotFound, err.Error())
-		} else if err.Error() == "reservation dates overlap with an existing booking" {
-			sendErrorResponse(w, http.StatusBadRequest, err.Error())
-		} else {
-			sendErrorResponse(w, http.StatusBadRequest, err.Error())
-		}
+		sendServiceError(w, err)
 		return
 	}
 
 	// Return the created reservation
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(reservation)
+	sendFieldsResponse(w, http.StatusCreated, reservation, fields, "")
 }
 
 // UpdateReservation handles PUT requests to update an existing reservation
@@ -101,6 +148,13 @@ func (h *ReservationHandler) UpdateReservation(w http.ResponseWriter, r *http.Re
 	hotelID := vars["hotelId"]
 	reservationID := vars["reservationId"]
 
+	// Parse the fields to return
+	fields, problems := parseResourceFields(r, reservationFields)
+	if problems != nil {
+		sendValidationError(w, problems)
+		return
+	}
+
 	// Parse request body
 	var req models.UpdateReservationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -109,29 +163,51 @@ func (h *ReservationHandler) UpdateReservation(w http.ResponseWriter, r *http.Re
 	}
 
 	// Validate required fields
-	if req.CustomerName == "" || req.StartDate == "" || req.EndDate == "" {
-		sendErrorResponse(w, http.StatusBadRequest, "CustomerName, StartDate, and EndDate are required fields")
+	if err := validateRequiredReservationFields(req.CustomerName, req.StartDate, req.EndDate); err != nil {
+		sendValidationError(w, err)
 		return
 	}
 
 	// Update the reservation
 	reservation, err := h.Service.UpdateReservation(hotelID, reservationID, req)
 	if err != nil {
-		if err.Error() == "hotel not found" || err.Error() == "reservation not found" {
-			sendErrorResponse(w, http.StatusNotFound, err.Error())
-		} else if err.Error() == "reservation dates overlap with an existing booking" {
-			sendErrorResponse(w, http.StatusBadRequest, err.Error())
-		} else {
-			sendErrorResponse(w, http.StatusBadRequest, err.Error())
-		}
+		sendServiceError(w, err)
 		return
 	}
 
 	// Return the updated reservation
-	sendJSONResponse(w, reservation)
+	sendFieldsResponse(w, http.StatusOK, reservation, fields, "")
 }
 
-// DeleteReservation handles DELETE requests to remove a reservation
+// TransitionReservation returns a handler for POST requests applying an action to a reservation
+// (see models.Transitions). Actions not allowed in the reservation's status return 409.
+func (h *ReservationHandler) TransitionReservation(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get parameters from URL
+		vars := mux.Vars(r)
+		hotelID := vars["hotelId"]
+		reservationID := vars["reservationId"]
+
+		// Parse the fields to return
+		fields, problems := parseResourceFields(r, reservationFields)
+		if problems != nil {
+			sendValidationError(w, problems)
+			return
+		}
+
+		// Move the reservation to its next status
+		reservation, err := h.Service.TransitionReservation(hotelID, reservationID, action)
+		if err != nil {
+			sendServiceError(w, err)
+			return
+		}
+
+		// Return the updated reservation
+		sendFieldsResponse(w, http.StatusOK, reservation, fields, "")
+	}
+}
+
+// DeleteReservation handles DELETE requests to purge a reservation, an admin operation distinct from cancelling
 func (h *ReservationHandler) DeleteReservation(w http.ResponseWriter, r *http.Request) {
 	// Get parameters from URL
 	vars := mux.Vars(r)
@@ -141,14 +217,127 @@ func (h *ReservationHandler) DeleteReservation(w http.ResponseWriter, r *http.Re
 	// Delete the reservation
 	err := h.Service.DeleteReservation(hotelID, reservationID)
 	if err != nil {
-		if err.Error() == "hotel not found" || err.Error() == "reservation not found" {
-			sendErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete reservation")
-		}
+		sendServiceError(w, err)
 		return
 	}
 
 	// Return success with no content
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetRoomTypes handles GET requests for the room types of a hotel.
+// With checkIn and checkOut (YYYY-MM-DD), each room type has the number of its rooms left for that stay.
+func (h *ReservationHandler) GetRoomTypes(w http.ResponseWriter, r *http.Request) {
+	// Get hotelId from URL parameters
+	vars := mux.Vars(r)
+	hotelID := vars["hotelId"]
+
+	// The stay is optional, but its dates must be given together
+	query := r.URL.Query()
+	checkIn, checkOut := query.Get("checkIn"), query.Get("checkOut")
+	if (checkIn == "") != (checkOut == "") {
+		sendValidationError(w, &services.ValidationError{Fields: []models.FieldError{{Field: "checkIn", Message: "checkIn and checkOut must be given together"}}})
+		return
+	}
+
+	// Get the room types, and the rooms left for the stay
+	roomTypes, err := h.Service.GetRoomTypes(hotelID, checkIn, checkOut)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Return results
+	sendJSONResponse(w, models.RoomTypeResponse{HotelID: hotelID, CheckIn: checkIn, CheckOut: checkOut, RoomTypes: roomTypes})
+}
+
+// Formats of the availability calendar
+const (
+	availabilityNights = "nights" // one entry per night, the default
+	availabilityRuns   = "runs"   // runs of consecutive nights with the same status
+)
+
+// GetAvailability handles GET requests for the availability calendar of a hotel, one entry per night
+// from the night of from to the night before to. roomType only counts the rooms of that type, and
+// format=runs returns runs of nights with the same availability instead.
+func (h *ReservationHandler) GetAvailability(w http.ResponseWriter, r *http.Request) {
+	// Get hotelId from URL parameters
+	vars := mux.Vars(r)
+	hotelID := vars["hotelId"]
+
+	// The range is required
+	query := r.URL.Query()
+	from, to := query.Get("from"), query.Get("to")
+	problems := &services.ValidationError{}
+	if from == "" {
+		problems.Add("from", "is required")
+	}
+	if to == "" {
+		problems.Add("to", "is required")
+	}
+	format := query.Get("format")
+	if format != "" && format != availabilityNights && format != availabilityRuns {
+		problems.Add("format", "must be nights or runs")
+	}
+	if len(problems.Fields) > 0 {
+		sendValidationError(w, problems)
+		return
+	}
+
+	// Get the calendar from the reservations
+	roomType := query.Get("roomType")
+	nights, err := h.Service.GetAvailability(hotelID, from, to, roomType)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Return the nights, or their runs
+	response := models.AvailabilityResponse{HotelID: hotelID, From: from, To: to, RoomType: roomType}
+	if format == availabilityRuns {
+		response.Runs = models.CompactNights(nights)
+	} else {
+		response.Nights = nights
+	}
+	sendJSONResponse(w, response)
+}
+
+// parseStatusParam parses the comma-separated statuses of a reservation listing, or returns nil when none are given
+func parseStatusParam(param string) (map[string]bool, *services.ValidationError) {
+	if param == "" {
+		return nil, nil
+	}
+
+	statuses := map[string]bool{}
+	problems := &services.ValidationError{}
+	for _, status := range strings.Split(param, ",") {
+		status = strings.TrimSpace(status)
+		if !models.IsReservationStatus(status) {
+			problems.Add("status", fmt.Sprintf("unknown status %q, must be one of: %s", status, strings.Join(models.ReservationStatuses, ", ")))
+			continue
+		}
+		statuses[status] = true
+	}
+	if len(problems.Fields) > 0 {
+		return nil, problems
+	}
+	return statuses, nil
+}
+
+// validateRequiredReservationFields checks that the fields required to book a reservation are present
+func validateRequiredReservationFields(customerName, startDate, endDate string) *services.ValidationError {
+	problems := &services.ValidationError{}
+	if customerName == "" {
+		problems.Add("customerName", "is required")
+	}
+	if startDate == "" {
+		problems.Add("startDate", "is required")
+	}
+	if endDate == "" {
+		problems.Add("endDate", "is required")
+	}
+	if len(problems.Fields) == 0 {
+		return nil
+	}
+	return problems
+}
@@ -0,0 +1,627 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
+)
+
+// defaultSearchLimit is the page size used when no limit is given
+const defaultSearchLimit = 20
+
+// defaultClusterZoom is the zoom level used by lenient cluster requests without a valid zoom
+const defaultClusterZoom = 10
+
+// maxClusterZoom is the deepest supported map zoom level
+const maxClusterZoom = 20
+
+// maxQueryLength is the longest accepted full-text query, in characters
+const maxQueryLength = 200
+
+// defaultSuggestLimit and maxSuggestLimit bound the number of autocomplete suggestions
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// filterParamNames lists the query parameters filtering hotels, shared by search and clusters
+var filterParamNames = []string{
+	"q", "name", "city", "countryCode", "minRate", "maxRate", "minRating", "maxRating", "amenityMask", "amenities", "amenityMatch", "bbox", "checkIn", "checkOut", "roomType",
+}
+
+// searchParamNames lists the query parameters accepted by hotel search
+var searchParamNames = paramNames(filterParamNames, "limit", "offset", "near", "radius", "unit", "sort", "cursor", "meta", "facets", "fields")
+
+// clusterParamNames lists the query parameters accepted by hotel clusters
+var clusterParamNames = paramNames(filterParamNames, "zoom")
+
+// facetParamNames lists the query parameters accepted by facet counts
+var facetParamNames = paramNames(filterParamNames, "near", "radius", "facets")
+
+// suggestParamNames lists the query parameters accepted by autocomplete suggestions
+var suggestParamNames = paramNames(nil, "q", "limit")
+
+// paramNames builds a set of parameter names
+func paramNames(common []string, extra ...string) map[string]bool {
+	names := make(map[string]bool, len(common)+len(extra))
+	for _, name := range append(append([]string{}, common...), extra...) {
+		names[name] = true
+	}
+	return names
+}
+
+// searchOptions are the parts of a search request shaping the response rather than selecting hotels
+type searchOptions struct {
+	meta   bool     // add the pagination metadata (meta=true)
+	facets []string // facets to count (facets=)
+	fields fieldSet // fields to keep in each hotel (fields=)
+}
+
+// parseSearchParams extracts search parameters from the HTTP request, and the response options.
+//
+// In strict mode every out-of-spec or unknown parameter is reported in the returned
+// ValidationError. In lenient mode invalid parameters are silently ignored.
+func parseSearchParams(r *http.Request, lenient bool) (models.SearchParams, searchOptions, *services.ValidationError) {
+	p := newQueryParser(r.URL.Query(), lenient)
+	p.rejectUnknown(searchParamNames)
+
+	// Parse filters
+	params := parseFilterParams(p)
+
+	// Parse geo search parameters
+	parseGeoParams(p, &params)
+
+	// Parse the result order
+	params.Sort = parseSortParam(p, params.Near != nil)
+
+	// Parse pagination parameters
+	params.Limit = defaultSearchLimit
+	if limit, ok := p.int("limit", 1, math.MaxInt32); ok {
+		params.Limit = limit
+	}
+	params.Offset, _ = p.int("offset", 0, math.MaxInt32)
+
+	// Parse cursor pagination, which replaces the offset
+	if raw := p.string("cursor"); raw != "" {
+		if cursor, ok := decodeCursor(raw, p.query); !ok {
+			p.fail("cursor", "is invalid or belongs to a different search")
+		} else if p.query.Get("offset") != "" {
+			p.fail("offset", "cannot be combined with cursor")
+		} else {
+			params.Cursor = cursor
+		}
+	}
+
+	// Parse the response options
+	var options searchOptions
+	options.meta, _ = p.bool("meta")
+	params.CountTotal = options.meta
+	options.facets = parseFacetsParam(p)
+	options.fields = parseFieldsParam(p, hotelResultFields)
+
+	return params, options, p.result()
+}
+
+// parseFacetParams extracts the filters and facets of a facet count request; all facets are counted by default
+func parseFacetParams(r *http.Request, lenient bool) (models.SearchParams, []string, *services.ValidationError) {
+	p := newQueryParser(r.URL.Query(), lenient)
+	p.rejectUnknown(facetParamNames)
+
+	params := parseFilterParams(p)
+	parseGeoParams(p, &params)
+
+	facets := parseFacetsParam(p)
+	if facets == nil {
+		facets = models.FacetNames
+	}
+
+	return params, facets, p.result()
+}
+
+// parseFacetsParam reads facets=name,name,...
+func parseFacetsParam(p *queryParser) []string {
+	raw := p.string("facets")
+	if raw == "" {
+		return nil
+	}
+
+	var facets []string
+	seen := map[string]bool{}
+	for _, facet := range strings.Split(raw, ",") {
+		if !isFacetName(facet) {
+			p.fail("facets", fmt.Sprintf("unknown facet %q, must be one of: %s", facet, strings.Join(models.FacetNames, ", ")))
+			return nil
+		}
+		if !seen[facet] {
+			seen[facet] = true
+			facets = append(facets, facet)
+		}
+	}
+	return facets
+}
+
+func isFacetName(name string) bool {
+	for _, allowed := range models.FacetNames {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClusterParams extracts the filters and zoom level of a cluster request
+func parseClusterParams(r *http.Request, lenient bool) (models.SearchParams, int, *services.ValidationError) {
+	p := newQueryParser(r.URL.Query(), lenient)
+	p.rejectUnknown(clusterParamNames)
+
+	params := parseFilterParams(p)
+
+	zoom := defaultClusterZoom
+	if p.query.Get("zoom") == "" {
+		p.fail("zoom", "is required")
+	} else if value, ok := p.int("zoom", 0, maxClusterZoom); ok {
+		zoom = value
+	}
+
+	return params, zoom, p.result()
+}
+
+// parseSuggestParams extracts the partial query and limit of a suggestion request
+func parseSuggestParams(r *http.Request, lenient bool) (string, int, *services.ValidationError) {
+	p := newQueryParser(r.URL.Query(), lenient)
+	p.rejectUnknown(suggestParamNames)
+
+	query := strings.TrimSpace(p.string("q"))
+	switch {
+	case query == "":
+		p.fail("q", "is required")
+	case utf8.RuneCountInString(query) > maxQueryLength:
+		p.fail("q", fmt.Sprintf("must be at most %d characters long", maxQueryLength))
+		query = ""
+	}
+
+	limit := defaultSuggestLimit
+	if value, ok := p.int("limit", 1, maxSuggestLimit); ok {
+		limit = value
+	}
+
+	return query, limit, p.result()
+}
+
+// parseAmenityLanguage picks the language of the amenity labels from lang=, else from Accept-Language
+func parseAmenityLanguage(r *http.Request, lenient bool) (string, *services.ValidationError) {
+	p := newQueryParser(r.URL.Query(), lenient)
+	p.rejectUnknown(map[string]bool{"lang": true})
+
+	if lang := p.string("lang"); lang != "" {
+		for _, language := range models.AmenityLanguages {
+			if strings.EqualFold(lang, language) {
+				return language, p.result()
+			}
+		}
+		p.fail("lang", "must be one of: "+strings.Join(models.AmenityLanguages, ", "))
+	}
+
+	return negotiateLanguage(r.Header.Get("Accept-Language"), models.AmenityLanguages), p.result()
+}
+
+// negotiateLanguage picks the supported language an Accept-Language header prefers,
+// defaulting to the first supported one. Regional variants such as es-MX match their language.
+func negotiateLanguage(header string, supported []string) string {
+	best, bestQuality := supported[0], 0.0
+	for _, accepted := range strings.Split(header, ",") {
+		parts := strings.Split(strings.TrimSpace(accepted), ";")
+		tag := strings.ToLower(strings.TrimSpace(parts[0]))
+		quality := 1.0
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = value
+				}
+			}
+		}
+		language, _, _ := strings.Cut(tag, "-")
+		for _, candidate := range supported {
+			if language == candidate && quality > bestQuality {
+				best, bestQuality = candidate, quality
+			}
+		}
+	}
+	return best
+}
+
+// parseFilterParams reads the parameters filtering hotels
+func parseFilterParams(p *queryParser) models.SearchParams {
+	params := models.SearchParams{
+		Name: p.string("name"),
+		City: p.string("city"),
+	}
+
+	// Parse the full-text query
+	if query := strings.TrimSpace(p.string("q")); query != "" {
+		switch {
+		case utf8.RuneCountInString(query) > maxQueryLength:
+			p.fail("q", fmt.Sprintf("must be at most %d characters long", maxQueryLength))
+		case strings.IndexFunc(query, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0:
+			p.fail("q", "must contain at least one word")
+		default:
+			params.Query = query
+		}
+	}
+
+	if countryCode := p.string("countryCode"); countryCode != "" {
+		if models.CountryCodePattern.MatchString(countryCode) {
+			params.CountryCode = countryCode
+		} else {
+			p.fail("countryCode", "must be a 2-letter uppercase ISO code")
+		}
+	}
+
+	// Parse numeric parameters
+	params.MinRate, _ = p.float("minRate", 0, math.Inf(1))
+	params.MaxRate, _ = p.float("maxRate", 0, math.Inf(1))
+	params.MinRating, _ = p.float("minRating", 1, 5)
+	params.MaxRating, _ = p.float("maxRating", 1, 5)
+	params.AmenityMask, _ = p.int("amenityMask", 0, math.MaxInt32)
+	parseAmenitiesParam(p, &params)
+
+	// Check min/max consistency
+	if params.MinRate > 0 && params.MaxRate > 0 && params.MinRate > params.MaxRate {
+		p.fail("minRate", "must be less than or equal to maxRate")
+		params.MinRate, params.MaxRate = 0, 0
+	}
+	if params.MinRating > 0 && params.MaxRating > 0 && params.MinRating > params.MaxRating {
+		p.fail("minRating", "must be less than or equal to maxRating")
+		params.MinRating, params.MaxRating = 0, 0
+	}
+
+	// Parse the stay of availability searches
+	parseStayParams(p, &params)
+
+	// Parse the map viewport
+	if raw := p.string("bbox"); raw != "" {
+		if box, ok := parseBoundingBox(raw); ok {
+			params.BBox = &box
+		} else {
+			p.fail("bbox", "must be minLat,minLng,maxLat,maxLng with minLat not above maxLat, latitudes between -90 and 90 and longitudes between -180 and 180")
+		}
+	}
+
+	return params
+}
+
+// parseStayParams reads checkIn=YYYY-MM-DD, checkOut=YYYY-MM-DD and the optional roomType, which restrict
+// the search to hotels with a room of that type (by default their first one, like bookings) free for the stay
+func parseStayParams(p *queryParser, params *models.SearchParams) {
+	checkIn, checkInOK := p.date("checkIn")
+	checkOut, checkOutOK := p.date("checkOut")
+	roomType := p.string("roomType")
+
+	switch {
+	case p.query.Get("checkIn") == "" && p.query.Get("checkOut") == "":
+		if roomType != "" {
+			p.fail("roomType", "requires checkIn and checkOut")
+		}
+	case p.query.Get("checkOut") == "":
+		p.fail("checkIn", "requires checkOut")
+	case p.query.Get("checkIn") == "":
+		p.fail("checkOut", "requires checkIn")
+	case !checkInOK || !checkOutOK:
+	case !checkOut.After(checkIn):
+		p.fail("checkOut", "must be after checkIn")
+	default:
+		params.CheckIn = p.query.Get("checkIn")
+		params.CheckOut = p.query.Get("checkOut")
+		params.RoomType = roomType
+	}
+}
+
+// parseAmenitiesParam reads amenities=code,code,... and amenityMatch=all|any.
+// With all (the default) the amenities are added to the amenity mask; with any, hotels need only one of them.
+func parseAmenitiesParam(p *queryParser, params *models.SearchParams) {
+	match := p.string("amenityMatch")
+	if match != "" && match != "all" && match != "any" {
+		p.fail("amenityMatch", "must be all or any")
+		match = ""
+	}
+
+	raw := p.string("amenities")
+	if raw == "" {
+		if match != "" {
+			p.fail("amenityMatch", "requires amenities")
+		}
+		return
+	}
+
+	mask := 0
+	for _, code := range strings.Split(raw, ",") {
+		bit, ok := models.AmenityBit(code)
+		if !ok {
+			p.fail("amenities", fmt.Sprintf("unknown amenity %q, see /api/amenities", code))
+			return
+		}
+		mask |= bit
+	}
+	if match == "any" {
+		params.AnyAmenityMask = mask
+	} else {
+		params.AmenityMask |= mask
+	}
+}
+
+// parseBoundingBox parses "minLat,minLng,maxLat,maxLng".
+// minLng may be greater than maxLng for boxes crossing the antimeridian.
+func parseBoundingBox(value string) (models.BoundingBox, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return models.BoundingBox{}, false
+	}
+	min, minOK := parseLatLng(parts[0] + "," + parts[1])
+	max, maxOK := parseLatLng(parts[2] + "," + parts[3])
+	if !minOK || !maxOK || min.Latitude > max.Latitude {
+		return models.BoundingBox{}, false
+	}
+	return models.BoundingBox{
+		MinLatitude:  min.Latitude,
+		MinLongitude: min.Longitude,
+		MaxLatitude:  max.Latitude,
+		MaxLongitude: max.Longitude,
+	}, true
+}
+
+// parseGeoParams reads near=lat,lng, radius=2km (or mi) and unit=km|mi.
+// Distances are reported in the radius unit, unless unit says otherwise, and default to kilometres.
+func parseGeoParams(p *queryParser, params *models.SearchParams) {
+	if near := p.string("near"); near != "" {
+		if location, ok := parseLatLng(near); ok {
+			params.Near = &location
+		} else {
+			p.fail("near", "must be latitude,longitude with latitude between -90 and 90 and longitude between -180 and 180")
+		}
+	}
+
+	unit := models.DistanceUnitKm
+	if raw := p.string("radius"); raw != "" {
+		radius, radiusUnit, ok := parseDistance(raw)
+		switch {
+		case !ok:
+			p.fail("radius", "must be a positive distance in km or mi, e.g. 2km")
+		case params.Near == nil:
+			p.fail("radius", "requires near")
+		default:
+			params.Radius = radius
+			unit = radiusUnit
+		}
+	}
+
+	if raw := p.string("unit"); raw != "" {
+		switch strings.ToLower(raw) {
+		case "km":
+			unit = models.DistanceUnitKm
+		case "mi":
+			unit = models.DistanceUnitMi
+		default:
+			p.fail("unit", "must be km or mi")
+		}
+	}
+
+	if params.Near != nil {
+		params.DistanceUnit = unit
+	}
+}
+
+// parseSortParam reads sort=field,-field,... where a leading "-" sorts in descending order.
+// distance is only allowed for geo searches.
+func parseSortParam(p *queryParser, geo bool) []models.SortKey {
+	raw := p.string("sort")
+	if raw == "" {
+		return nil
+	}
+
+	var keys []models.SortKey
+	seen := map[string]bool{}
+	for _, item := range strings.Split(raw, ",") {
+		key := models.SortKey{Field: strings.TrimPrefix(item, "-"), Descending: strings.HasPrefix(item, "-")}
+		switch {
+		case !isSortField(key.Field):
+			p.fail("sort", fmt.Sprintf("unknown sort field %q, must be one of: %s", item, strings.Join(models.SortFields, ", ")))
+			return nil
+		case key.Field == models.SortDistance && !geo:
+			p.fail("sort", "distance requires near")
+			return nil
+		case seen[key.Field]:
+			p.fail("sort", fmt.Sprintf("lists %s more than once", key.Field))
+			return nil
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func isSortField(field string) bool {
+	for _, allowed := range models.SortFields {
+		if field == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLatLng parses a "latitude,longitude" pair
+func parseLatLng(value string) (models.Location, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return models.Location{}, false
+	}
+	lat, latErr := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lng, lngErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if latErr != nil || lngErr != nil || !(lat >= -90 && lat <= 90) || !(lng >= -180 && lng <= 180) {
+		return models.Location{}, false
+	}
+	return models.Location{Latitude: lat, Longitude: lng}, true
+}
+
+// parseDistance parses a distance such as "2km", "1.5mi" or "3" (kilometres),
+// returning it in kilometres together with the unit it was given in
+func parseDistance(value string) (km float64, unit string, ok bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	unit = models.DistanceUnitKm
+	factor := 1.0
+	switch {
+	case strings.HasSuffix(value, "km"):
+		value = strings.TrimSuffix(value, "km")
+	case strings.HasSuffix(value, "mi"):
+		value = strings.TrimSuffix(value, "mi")
+		unit = models.DistanceUnitMi
+		factor = models.KmPerMile
+	}
+
+	distance, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || !(distance > 0) || math.IsInf(distance, 0) {
+		return 0, "", false
+	}
+	return distance * factor, unit, true
+}
+
+// queryParser reads typed query parameters, collecting the problems found.
+// When lenient, problems are not reported and invalid values are simply ignored.
+type queryParser struct {
+	query    url.Values
+	lenient  bool
+	problems *services.ValidationError
+}
+
+// newQueryParser creates a queryParser for the given query values
+func newQueryParser(query url.Values, lenient bool) *queryParser {
+	return &queryParser{
+		query:    query,
+		lenient:  lenient,
+		problems: &services.ValidationError{},
+	}
+}
+
+// fail records a problem with a parameter
+func (p *queryParser) fail(name, message string) {
+	if !p.lenient {
+		p.problems.Add(name, message)
+	}
+}
+
+// result returns the problems found, or nil if there are none
+func (p *queryParser) result() *services.ValidationError {
+	if len(p.problems.Fields) == 0 {
+		return nil
+	}
+	return p.problems
+}
+
+// rejectUnknown reports every parameter that is not in the allowed set
+func (p *queryParser) rejectUnknown(allowed map[string]bool) {
+	names := make([]string, 0, len(p.query))
+	for name := range p.query {
+		if !allowed[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.fail(name, "unknown parameter")
+	}
+}
+
+// string returns a parameter value, or "" if it is absent.
+// Parameters given more than once are reported.
+func (p *queryParser) string(name string) string {
+	values := p.query[name]
+	if len(values) == 0 {
+		return ""
+	}
+	if len(values) > 1 {
+		p.fail(name, "must be given only once")
+	}
+	return values[0]
+}
+
+// bool parses a boolean parameter; ok is false if it is absent or invalid
+func (p *queryParser) bool(name string) (value bool, ok bool) {
+	raw := p.string(name)
+	if raw == "" {
+		return false, false
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		p.fail(name, "must be true or false")
+		return false, false
+	}
+	return value, true
+}
+
+// float parses a number parameter within [min, max]; ok is false if it is absent or invalid
+func (p *queryParser) float(name string, min, max float64) (value float64, ok bool) {
+	raw := p.string(name)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		p.fail(name, "must be a number")
+		return 0, false
+	}
+	if value < min || value > max {
+		p.fail(name, rangeMessage("a number", min, max))
+		return 0, false
+	}
+	return value, true
+}
+
+// date parses a YYYY-MM-DD date parameter; ok is false if it is absent or invalid
+func (p *queryParser) date(name string) (value time.Time, ok bool) {
+	raw := p.string(name)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	value, err := models.ParseDate(raw)
+	if err != nil {
+		p.fail(name, "must be a date in YYYY-MM-DD format")
+		return time.Time{}, false
+	}
+	return value, true
+}
+
+// int parses an integer parameter within [min, max]; ok is false if it is absent or invalid
+func (p *queryParser) int(name string, min, max int) (value int, ok bool) {
+	raw := p.string(name)
+	if raw == "" {
+		return 0, false
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		p.fail(name, "must be an integer")
+		return 0, false
+	}
+	if value < min || value > max {
+		p.fail(name, rangeMessage("an integer", float64(min), float64(max)))
+		return 0, false
+	}
+	return value, true
+}
+
+// rangeMessage describes the accepted range of a numeric parameter
+func rangeMessage(kind string, min, max float64) string {
+	if math.IsInf(max, 1) || max >= math.MaxInt32 {
+		return fmt.Sprintf("must be %s greater than or equal to %v", kind, min)
+	}
+	return fmt.Sprintf("must be %s between %v and %v", kind, min, max)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
+)
+
+func TestParseSearchParamsStrict(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string // fields reported, none for a valid search
+	}{
+		{"valid", "city=Paris&countryCode=FR&minRate=100&maxRate=200&minRating=3&limit=5&offset=10&meta=true", nil},
+		{"unknown parameter", "city=Paris&sity=Paris", []string{"sity"}},
+		{"unknown parameters sorted", "b=1&a=2", []string{"a", "b"}},
+		{"malformed number", "minRate=cheap", []string{"minRate"}},
+		{"malformed integer", "limit=5.5", []string{"limit"}},
+		{"number out of range", "minRating=6", []string{"minRating"}},
+		{"zero limit", "limit=0", []string{"limit"}},
+		{"min rate above max rate", "minRate=200&maxRate=100", []string{"minRate"}},
+		{"min rating above max rating", "minRating=4&maxRating=2", []string{"minRating"}},
+		{"lowercase country code", "countryCode=fr", []string{"countryCode"}},
+		{"repeated parameter", "city=Paris&city=Rome", []string{"city"}},
+		{"malformed boolean", "meta=yes", []string{"meta"}},
+		{"check-in without check-out", "checkIn=2026-07-01", []string{"checkIn"}},
+		{"several problems", "minRate=x&maxRating=0&foo=1", []string{"foo", "minRate", "maxRating"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := parseSearchParams(httptest.NewRequest("GET", "/api/hotels?"+tc.query, nil), false)
+			var got []string
+			if err != nil {
+				for _, field := range err.Fields {
+					got = append(got, field.Field)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("reported fields = %v, want %v (%v)", got, tc.want, err)
+			}
+		})
+	}
+}
+
+func TestParseSearchParamsLenient(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  models.SearchParams
+	}{
+		{"valid", "city=Paris&minRate=100&maxRate=200&limit=5&offset=10",
+			models.SearchParams{City: "Paris", MinRate: 100, MaxRate: 200, Limit: 5, Offset: 10}},
+		{"unknown parameter", "city=Paris&sity=Rome", models.SearchParams{City: "Paris", Limit: defaultSearchLimit}},
+		{"malformed number", "minRate=cheap&maxRate=200", models.SearchParams{MaxRate: 200, Limit: defaultSearchLimit}},
+		{"number out of range", "minRating=6&maxRating=4", models.SearchParams{MaxRating: 4, Limit: defaultSearchLimit}},
+		{"zero limit", "limit=0", models.SearchParams{Limit: defaultSearchLimit}},
+		{"min rate above max rate", "minRate=200&maxRate=100", models.SearchParams{Limit: defaultSearchLimit}},
+		{"lowercase country code", "countryCode=fr&city=Paris", models.SearchParams{City: "Paris", Limit: defaultSearchLimit}},
+		{"check-in without check-out", "checkIn=2026-07-01", models.SearchParams{Limit: defaultSearchLimit}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			params, _, err := parseSearchParams(httptest.NewRequest("GET", "/api/hotels?"+tc.query, nil), true)
+			if err != nil {
+				t.Fatalf("lenient parsing reported %v", err)
+			}
+			if !reflect.DeepEqual(params, tc.want) {
+				t.Errorf("params = %+v, want %+v", params, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetHotelsRejectsInvalidSearches(t *testing.T) {
+	hotels := services.NewHotelService()
+	handler := NewHotelHandler(hotels, services.NewReservationService(hotels))
+
+	for _, lenient := range []bool{false, true} {
+		handler.LenientSearch = lenient
+		want := http.StatusBadRequest
+		if lenient {
+			want = http.StatusOK
+		}
+		for _, query := range []string{"sity=Paris", "minRate=cheap", "minRate=200&maxRate=100"} {
+			rec := httptest.NewRecorder()
+			handler.GetHotels(rec, httptest.NewRequest("GET", "/api/hotels?"+query, nil))
+			if rec.Code != want {
+				t.Errorf("lenient=%v, %s: status = %d, want %d", lenient, query, rec.Code, want)
+			}
+		}
+	}
+}
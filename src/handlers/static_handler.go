@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultStaticMaxAge is the Cache-Control max-age used when none is configured
+const defaultStaticMaxAge = time.Hour
+
+// StaticHandler serves static assets (e.g. hotel thumbnails) from a root directory
+type StaticHandler struct {
+	Root   string
+	MaxAge time.Duration
+}
+
+// NewStaticHandler creates a new instance of StaticHandler serving files under root
+func NewStaticHandler(root string) *StaticHandler {
+	return &StaticHandler{
+		Root:   root,
+		MaxAge: defaultStaticMaxAge,
+	}
+}
+
+// ServeHTTP handles GET and HEAD requests for static assets.
+// Conditional requests (If-None-Match, If-Modified-Since), Range requests and
+// content type detection are delegated to http.ServeContent.
+func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	// Resolve the requested file, rejecting anything outside the asset root
+	filePath, ok := h.resolve(r.URL.Path)
+	if !ok {
+		sendErrorResponse(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		sendErrorResponse(w, http.StatusNotFound, "File not found")
+		return
+	}
+	defer file.Close()
+
+	// Directory listing is never allowed
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		sendErrorResponse(w, http.StatusNotFound, "File not found")
+		return
+	}
+
+	// Set caching headers; ServeContent uses ETag for If-None-Match and If-Range
+	w.Header().Set("ETag", fileETag(info))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.MaxAge.Seconds())))
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// resolve maps a URL path to a file path inside the asset root.
+// It returns false for hidden files and for paths escaping the root (including via symlinks).
+func (h *StaticHandler) resolve(urlPath string) (string, bool) {
+	cleaned := path.Clean("/" + urlPath)
+	for _, segment := range strings.Split(cleaned, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", false
+		}
+	}
+
+	root, err := filepath.Abs(h.Root)
+	if err != nil {
+		return "", false
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", false
+	}
+
+	filePath, err := filepath.EvalSymlinks(filepath.Join(root, filepath.FromSlash(cleaned)))
+	if err != nil {
+		return "", false
+	}
+
+	// Make sure the resolved file is still below the root
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return filePath, true
+}
+
+// fileETag builds a strong ETag from the file's size and modification time
+func fileETag(info os.FileInfo) string {
+	return fmt.Sprintf("\"%x-%x\"", info.ModTime().UnixNano(), info.Size())
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// staticRoot creates an asset root with a file, a subdirectory, hidden files and symlinks,
+// next to a file outside the root
+func staticRoot(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "public")
+	files := map[string]string{
+		"outside.txt":              "outside the root",
+		"public/hotel.txt":         "hotel thumbnail",
+		"public/thumbnails/a.txt":  "thumbnail a",
+		"public/.git/config":       "[core]",
+		"public/thumbnails/.draft": "hidden",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "outside.txt"), filepath.Join(root, "escape.txt")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "hotel.txt"), filepath.Join(root, "thumbnails", "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestStaticHandler(t *testing.T) {
+	handler := NewStaticHandler(staticRoot(t))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"file", "GET", "/hotel.txt", http.StatusOK, "hotel thumbnail"},
+		{"nested file", "GET", "/thumbnails/a.txt", http.StatusOK, "thumbnail a"},
+		{"head", "HEAD", "/hotel.txt", http.StatusOK, ""},
+		{"symlink inside the root", "GET", "/thumbnails/link.txt", http.StatusOK, "hotel thumbnail"},
+		{"cleaned path", "GET", "/thumbnails/../hotel.txt", http.StatusOK, "hotel thumbnail"},
+		{"parent directory", "GET", "/../outside.txt", http.StatusNotFound, ""},
+		{"nested parent directory", "GET", "/thumbnails/../../outside.txt", http.StatusNotFound, ""},
+		{"symlink outside the root", "GET", "/escape.txt", http.StatusNotFound, ""},
+		{"hidden directory", "GET", "/.git/config", http.StatusNotFound, ""},
+		{"hidden file", "GET", "/thumbnails/.draft", http.StatusNotFound, ""},
+		{"directory", "GET", "/thumbnails", http.StatusNotFound, ""},
+		{"root directory", "GET", "/", http.StatusNotFound, ""},
+		{"missing file", "GET", "/missing.txt", http.StatusNotFound, ""},
+		{"method not allowed", "POST", "/hotel.txt", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.status)
+			}
+			if tc.status == http.StatusOK && rec.Body.String() != tc.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+func TestStaticHandlerHeaders(t *testing.T) {
+	handler := NewStaticHandler(staticRoot(t))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/hotel.txt", nil))
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("Cache-Control = %q, want public, max-age=3600", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain; charset=utf-8", got)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/hotel.txt", nil)
+	req.Header.Set("If-None-Match", etag)
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status = %d, want %d", rec.Code, http.StatusNotModified)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/hotel.txt", nil)
+	req.Header.Set("Range", "bytes=6-14")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "thumbnail" {
+		t.Errorf("Range: status = %d, body = %q, want %d and %q", rec.Code, rec.Body.String(), http.StatusPartialContent, "thumbnail")
+	}
+	if got := rec.Header().Get("Content-Range"); got != "bytes 6-14/15" {
+		t.Errorf("Content-Range = %q, want bytes 6-14/15", got)
+	}
+
+	// A stale If-Range validator gets the whole file
+	rec = httptest.NewRecorder()
+	req.Header.Set("If-Range", `"stale"`)
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "hotel thumbnail" {
+		t.Errorf("If-Range: status = %d, body = %q, want the whole file", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/hotel.txt", nil)
+	req.Header.Set("Range", "bytes=100-200")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("unsatisfiable Range: status = %d, want %d", rec.Code, http.StatusRequestedRangeNotSatisfiable)
+	}
+}
@@ -9,74 +9,46 @@ import (
 	"os/signal"
 	"time"
 
-	"github.com/gorilla/mux"
-	"github.com/vandimit/simple-hotels-mock-rest-api/src/handlers"
-	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
-	"github.com/vandimit/simple-hotels-mock-rest-api/src/utils"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/config"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/server"
 )
 
 func Main() {
-	// Create services
-	hotelService := services.NewHotelService()
-
-	// Load hotel data from JSON file
-	dataPath := "mock-data/hotels-data.json"
-	err := hotelService.LoadHotelsFromFile(dataPath)
+	// Load configuration from flags, environment and config file
+	cfg, printConfig, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Fatalf("Failed to load hotel data: %v", err)
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
 	}
 
-	// Initialize reservation service
-	reservationService := services.NewReservationService(hotelService)
-
-	// Create handlers
-	hotelHandler := handlers.NewHotelHandler(hotelService)
-	reservationHandler := handlers.NewReservationHandler(reservationService)
-
-	// Create router
-	router := mux.NewRouter()
-
-	// Add middleware
-	router.Use(utils.LoggingMiddleware)
-	router.Use(utils.CORSMiddleware)
-
-	// API routes with prefix
-	apiRouter := router.PathPrefix("/api").Subrouter()
-
-	// Register hotel routes
-	apiRouter.HandleFunc("/hotels", hotelHandler.GetHotels).Methods("GET")
-	apiRouter.HandleFunc("/hotels/{hotelId}", hotelHandler.GetHotelByID).Methods("GET")
-
-	// Register reservation routes
-	apiRouter.HandleFunc("/hotels/{hotelId}/reservations", reservationHandler.GetReservations).Methods("GET")
-	apiRouter.HandleFunc("/hotels/{hotelId}/reservations", reservationHandler.CreateReservation).Methods("POST")
-	apiRouter.HandleFunc("/hotels/{hotelId}/reservations/{reservationId}", reservationHandler.GetReservationByID).Methods("GET")
-	apiRouter.HandleFunc("/hotels/{hotelId}/reservations/{reservationId}", reservationHandler.UpdateReservation).Methods("PUT")
-	apiRouter.HandleFunc("/hotels/{hotelId}/reservations/{reservationId}", reservationHandler.DeleteReservation).Methods("DELETE")
+	// Build the API server
+	api, err := server.New(server.Options{Config: cfg})
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
 
 	// Set up server
-	srv := &http.Server{
-		Addr:         ":8080",
-		WriteTimeout: time.Second * 15,
-		ReadTimeout:  time.Second * 15,
-		IdleTimeout:  time.Second * 60,
-		Handler:      router,
-	}
+	srv := api.HTTPServer()
 
 	// Start server in a goroutine
 	go func() {
-		log.Println("Starting server on port 8080")
+		log.Printf("Starting server on %s", cfg.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Error starting server: %s\n", err)
 		}
 	}()
 
 	// Set up graceful shutdown
-	gracefulShutdown(srv)
+	gracefulShutdown(srv, time.Duration(cfg.ShutdownTimeout))
 }
 
 // gracefulShutdown handles graceful server shutdown on interrupt signal
-func gracefulShutdown(srv *http.Server) {
+func gracefulShutdown(srv *http.Server, timeout time.Duration) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 
@@ -84,7 +56,7 @@ func gracefulShutdown(srv *http.Server) {
 	<-c
 
 	// Create a deadline for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*15)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Shutdown server
@@ -0,0 +1,92 @@
+package models
+
+// Amenity is an entry of the amenity catalogue: a bit of Hotel.AmenityMask with its code and label
+type Amenity struct {
+	Bit   int    `json:"bit"`
+	Code  string `json:"code"`
+	Label string `json:"label"` // in the requested language
+}
+
+// AmenityResponse represents the response format for the amenity catalogue
+type AmenityResponse struct {
+	Language  string    `json:"language"`
+	Amenities []Amenity `json:"amenities"`
+}
+
+// AmenityLanguages lists the languages of the amenity labels; the first one is the default
+var AmenityLanguages = []string{"en", "es", "fr"}
+
+// amenity is the definition of a catalogue entry, with its label in every language
+type amenity struct {
+	bit    int
+	code   string
+	labels map[string]string
+}
+
+// amenities is the amenity catalogue, following the EAN amenity mask bit layout
+var amenities = []amenity{
+	{1 << 0, "business_center", map[string]string{"en": "Business center", "es": "Centro de negocios", "fr": "Centre d'affaires"}},
+	{1 << 1, "fitness_center", map[string]string{"en": "Fitness center", "es": "Gimnasio", "fr": "Salle de sport"}},
+	{1 << 2, "hot_tub", map[string]string{"en": "Hot tub", "es": "Bañera de hidromasaje", "fr": "Bain à remous"}},
+	{1 << 3, "wifi", map[string]string{"en": "Internet access", "es": "Acceso a internet", "fr": "Accès à internet"}},
+	{1 << 4, "kids_activities", map[string]string{"en": "Kids' activities", "es": "Actividades para niños", "fr": "Activités pour enfants"}},
+	{1 << 5, "kitchen", map[string]string{"en": "Kitchen or kitchenette", "es": "Cocina o cocina americana", "fr": "Cuisine ou kitchenette"}},
+	{1 << 6, "pets_allowed", map[string]string{"en": "Pets allowed", "es": "Se admiten mascotas", "fr": "Animaux acceptés"}},
+	{1 << 7, "pool", map[string]string{"en": "Pool", "es": "Piscina", "fr": "Piscine"}},
+	{1 << 8, "restaurant", map[string]string{"en": "Restaurant", "es": "Restaurante", "fr": "Restaurant"}},
+	{1 << 9, "spa", map[string]string{"en": "Spa", "es": "Spa", "fr": "Spa"}},
+	{1 << 10, "whirlpool_bath", map[string]string{"en": "Whirlpool bath", "es": "Bañera de hidromasaje en la habitación", "fr": "Baignoire balnéo"}},
+	{1 << 11, "breakfast", map[string]string{"en": "Breakfast", "es": "Desayuno", "fr": "Petit-déjeuner"}},
+	{1 << 12, "babysitting", map[string]string{"en": "Babysitting", "es": "Servicio de niñera", "fr": "Garde d'enfants"}},
+	{1 << 13, "jacuzzi", map[string]string{"en": "Jacuzzi", "es": "Jacuzzi", "fr": "Jacuzzi"}},
+	{1 << 14, "parking", map[string]string{"en": "Parking", "es": "Aparcamiento", "fr": "Parking"}},
+	{1 << 15, "room_service", map[string]string{"en": "Room service", "es": "Servicio de habitaciones", "fr": "Service en chambre"}},
+	{1 << 16, "accessible_path", map[string]string{"en": "Accessible path of travel", "es": "Recorrido accesible", "fr": "Parcours accessible"}},
+	{1 << 17, "accessible_bathroom", map[string]string{"en": "Accessible bathroom", "es": "Baño adaptado", "fr": "Salle de bain accessible"}},
+	{1 << 18, "roll_in_shower", map[string]string{"en": "Roll-in shower", "es": "Ducha a ras de suelo", "fr": "Douche à l'italienne"}},
+	{1 << 19, "accessible_parking", map[string]string{"en": "Accessible parking", "es": "Aparcamiento adaptado", "fr": "Parking accessible"}},
+	{1 << 20, "in_room_accessibility", map[string]string{"en": "In-room accessibility", "es": "Habitación adaptada", "fr": "Chambre accessible"}},
+	{1 << 21, "deaf_accessibility", map[string]string{"en": "Accessibility equipment for the deaf", "es": "Equipamiento para personas sordas", "fr": "Équipements pour personnes sourdes"}},
+	{1 << 22, "braille_signage", map[string]string{"en": "Braille or raised signage", "es": "Señalización en braille o en relieve", "fr": "Signalisation en braille ou en relief"}},
+	{1 << 23, "airport_shuttle", map[string]string{"en": "Free airport shuttle", "es": "Traslado gratuito al aeropuerto", "fr": "Navette aéroport gratuite"}},
+	{1 << 24, "indoor_pool", map[string]string{"en": "Indoor pool", "es": "Piscina cubierta", "fr": "Piscine intérieure"}},
+	{1 << 25, "outdoor_pool", map[string]string{"en": "Outdoor pool", "es": "Piscina al aire libre", "fr": "Piscine extérieure"}},
+	{1 << 26, "extended_parking", map[string]string{"en": "Extended parking", "es": "Aparcamiento de larga estancia", "fr": "Parking longue durée"}},
+	{1 << 27, "free_parking", map[string]string{"en": "Free parking", "es": "Aparcamiento gratuito", "fr": "Parking gratuit"}},
+}
+
+// amenityBits maps amenity codes to their bit
+var amenityBits = func() map[string]int {
+	bits := make(map[string]int, len(amenities))
+	for _, entry := range amenities {
+		bits[entry.code] = entry.bit
+	}
+	return bits
+}()
+
+// AmenityCatalogue returns the amenity catalogue with the labels of a language from AmenityLanguages
+func AmenityCatalogue(language string) []Amenity {
+	catalogue := make([]Amenity, len(amenities))
+	for i, entry := range amenities {
+		catalogue[i] = Amenity{Bit: entry.bit, Code: entry.code, Label: entry.labels[language]}
+	}
+	return catalogue
+}
+
+// AmenityBit returns the bit of an amenity code
+func AmenityBit(code string) (int, bool) {
+	bit, ok := amenityBits[code]
+	return bit, ok
+}
+
+// AmenityCodes decodes an amenity mask into the codes of its amenities, in catalogue order.
+// Bits missing from the catalogue are left out.
+func AmenityCodes(mask int) []string {
+	codes := []string{}
+	for _, entry := range amenities {
+		if mask&entry.bit != 0 {
+			codes = append(codes, entry.code)
+		}
+	}
+	return codes
+}
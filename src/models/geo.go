@@ -0,0 +1,61 @@
+package models
+
+// BoundingBox is a latitude/longitude rectangle, e.g. the viewport of a map.
+// MinLongitude is greater than MaxLongitude when the box crosses the antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64 `json:"minLatitude"`
+	MinLongitude float64 `json:"minLongitude"`
+	MaxLatitude  float64 `json:"maxLatitude"`
+	MaxLongitude float64 `json:"maxLongitude"`
+}
+
+// Contains reports whether a location is inside the box, edges included
+func (b BoundingBox) Contains(location Location) bool {
+	if location.Latitude < b.MinLatitude || location.Latitude > b.MaxLatitude {
+		return false
+	}
+	if b.MinLongitude <= b.MaxLongitude {
+		return location.Longitude >= b.MinLongitude && location.Longitude <= b.MaxLongitude
+	}
+	return location.Longitude >= b.MinLongitude || location.Longitude <= b.MaxLongitude
+}
+
+// HotelCluster is a group of nearby hotels, as drawn on a map at a given zoom level
+type HotelCluster struct {
+	ID         string      `json:"id"`
+	Count      int         `json:"count"`
+	Centroid   Location    `json:"centroid"`
+	Bounds     BoundingBox `json:"bounds"` // the grid cell holding the cluster
+	MinLowRate float64     `json:"minLowRate"`
+	MaxLowRate float64     `json:"maxLowRate"`
+	HotelID    string      `json:"hotelId,omitempty"` // set when the cluster holds a single hotel
+}
+
+// ClusterResponse represents the response format for hotel clusters
+type ClusterResponse struct {
+	Zoom     int            `json:"zoom"`
+	Total    int            `json:"total"` // number of hotels in all the clusters
+	Clusters []HotelCluster `json:"clusters"`
+}
+
+// GeoJSON types (RFC 7946)
+
+// FeatureCollection is a GeoJSON FeatureCollection
+type FeatureCollection struct {
+	Type     string    `json:"type"` // always "FeatureCollection"
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature
+type Feature struct {
+	Type       string      `json:"type"` // always "Feature"
+	ID         string      `json:"id,omitempty"`
+	Geometry   Point       `json:"geometry"`
+	Properties interface{} `json:"properties"`
+}
+
+// Point is a GeoJSON Point geometry
+type Point struct {
+	Type        string     `json:"type"`        // always "Point"
+	Coordinates [2]float64 `json:"coordinates"` // longitude, latitude
+}
@@ -1,5 +1,10 @@
 package models
 
+import "regexp"
+
+// CountryCodePattern matches a 2-letter ISO country code, as declared in the OpenAPI spec
+var CountryCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+
 // Hotel represents a hotel entity with all its properties
 type Hotel struct {
 	ID                   string   `json:"id"`
@@ -10,6 +15,7 @@ type Hotel struct {
 	Address1             string   `json:"address1"`
 	AirportCode          string   `json:"airportCode"`
 	AmenityMask          int      `json:"amenityMask"`
+	Amenities            []string `json:"amenities"` // amenity codes decoded from AmenityMask by the server
 	City                 string   `json:"city"`
 	ConfidenceRating     int      `json:"confidenceRating"`
 	CountryCode          string   `json:"countryCode"`
@@ -50,9 +56,162 @@ type HotelResponse struct {
 	Hotels []Hotel `json:"hotels"`
 }
 
-// ErrorResponse represents the error response format
-type ErrorResponse struct {
-	Code    int    `json:"code"`
+// HotelResult is a hotel returned by a search, with the values computed for that search
+type HotelResult struct {
+	Hotel
+	Distance     *float64 `json:"distance,omitempty"`     // distance to the near point, in DistanceUnit
+	DistanceUnit string   `json:"distanceUnit,omitempty"` // "KM" or "MI", like ProximityUnit
+	Score        *float64 `json:"score,omitempty"`        // relevance to the full-text query, higher is better
+	Available    *bool    `json:"available,omitempty"`    // free for the requested stay (availability searches only)
+	Nights       int      `json:"nights,omitempty"`       // number of nights of the requested stay
+}
+
+// HotelWithReservations is a hotel with its reservations embedded (include=reservations)
+type HotelWithReservations struct {
+	Hotel
+	Reservations []Reservation `json:"reservations"`
+}
+
+// Distance units, following the ProximityUnit convention
+const (
+	DistanceUnitKm = "KM"
+	DistanceUnitMi = "MI"
+
+	// KmPerMile converts miles to kilometres
+	KmPerMile = 1.609344
+)
+
+// HotelSearchResponse represents the response format for hotel searches.
+// The pagination metadata is only included when requested (meta=true), to keep the v1 format by default.
+type HotelSearchResponse struct {
+	Hotels []HotelResult            `json:"hotels"`
+	Facets map[string][]FacetBucket `json:"facets,omitempty"` // only when requested with facets=
+	*PageMeta
+}
+
+// PageMeta describes where a page of search results is in the full result list
+type PageMeta struct {
+	Total      int    `json:"total"`                // number of hotels matching the search
+	Limit      int    `json:"limit"`                // page size
+	Offset     int    `json:"offset"`               // number of matching hotels before the page
+	Next       string `json:"next,omitempty"`       // URL of the next page, absent on the last page
+	Prev       string `json:"prev,omitempty"`       // URL of the previous page, absent on the first page
+	NextCursor string `json:"nextCursor,omitempty"` // cursor of the next page
+	PrevCursor string `json:"prevCursor,omitempty"` // cursor of the previous page
+}
+
+// SearchCursor marks the edge of a page of search results in the search order, so the adjacent page
+// can be fetched consistently while hotels are inserted or deleted. It holds the order values of the
+// hotel at the edge; only the values used by the order are set.
+type SearchCursor struct {
+	Backward bool   `json:"b,omitempty"` // the page before the cursor rather than after it
+	Seq      int64  `json:"s"`           // insertion sequence, breaking ties of the default, relevance and distance orders
+	ID       string `json:"id"`          // hotel ID, breaking ties of explicit sorts
+
+	Score             float64 `json:"sc,omitempty"`
+	Distance          float64 `json:"km,omitempty"`
+	LowRate           float64 `json:"lr,omitempty"`
+	HighRate          float64 `json:"hr,omitempty"`
+	HotelRating       float64 `json:"hor,omitempty"`
+	TripAdvisorRating float64 `json:"tar,omitempty"`
+	Name              string  `json:"n,omitempty"`
+	Modified          int64   `json:"m,omitempty"`
+
+	Corpus *TextCorpus `json:"tc,omitempty"` // relevance orders only, see TextCorpus
+}
+
+// TextCorpus holds the statistics full-text relevance is computed from. Relevance cursors keep the ones
+// of the search's first page, so later pages score hotels the same way and the order does not shift
+// when hotels are inserted or deleted.
+type TextCorpus struct {
+	Documents int            `json:"n"`   // number of hotels
+	AvgLength float64        `json:"len"` // average weighted length of the hotels' text
+	Terms     map[string]int `json:"df"`  // number of hotels having each query term
+}
+
+// Search sort fields
+const (
+	SortLowRate           = "lowRate"
+	SortHighRate          = "highRate"
+	SortHotelRating       = "hotelRating"
+	SortTripAdvisorRating = "tripAdvisorRating"
+	SortName              = "name"
+	SortModified          = "modified"
+	SortDistance          = "distance" // geo searches only
+)
+
+// SortFields lists the fields hotel searches can be sorted by
+var SortFields = []string{
+	SortLowRate, SortHighRate, SortHotelRating, SortTripAdvisorRating, SortName, SortModified, SortDistance,
+}
+
+// SortKey orders search results by a field
+type SortKey struct {
+	Field      string `json:"field"`
+	Descending bool   `json:"descending,omitempty"`
+}
+
+// Search facets
+const (
+	FacetCity              = "city"
+	FacetCountryCode       = "countryCode"
+	FacetHotelRating       = "hotelRating"
+	FacetPropertyCategory  = "propertyCategory"
+	FacetAmenities         = "amenities"
+	FacetLowRate           = "lowRate"           // range buckets
+	FacetTripAdvisorRating = "tripAdvisorRating" // range buckets
+)
+
+// FacetNames lists the facets hotel searches can be counted by
+var FacetNames = []string{
+	FacetCity, FacetCountryCode, FacetHotelRating, FacetPropertyCategory, FacetAmenities, FacetLowRate, FacetTripAdvisorRating,
+}
+
+// FacetBucket counts the matching hotels having a facet value, or a value within a range
+type FacetBucket struct {
+	Value interface{} `json:"value,omitempty"` // value buckets only
+	From  *float64    `json:"from,omitempty"`  // range buckets only, inclusive
+	To    *float64    `json:"to,omitempty"`    // range buckets only, exclusive; absent on the last range
+	Count int         `json:"count"`
+}
+
+// FacetResponse represents the response format for facet counts
+type FacetResponse struct {
+	Total  int                      `json:"total"` // number of hotels matching all the filters
+	Facets map[string][]FacetBucket `json:"facets"`
+}
+
+// Suggestion types
+const (
+	SuggestionHotel    = "hotel"
+	SuggestionCity     = "city"
+	SuggestionLandmark = "landmark"
+)
+
+// Suggestion is an autocomplete completion for a partial search query
+type Suggestion struct {
+	Type    string `json:"type"`              // "hotel", "city" or "landmark"
+	Text    string `json:"text"`              // hotel name, city or landmark
+	HotelID string `json:"hotelId,omitempty"` // hotel suggestions only
+}
+
+// SuggestionResponse represents the response format for autocomplete suggestions
+type SuggestionResponse struct {
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// Problem represents the error response format (RFC 7807 problem details)
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a validation problem with a single field or parameter
+type FieldError struct {
+	Field   string `json:"field"`
 	Message string `json:"message"`
 }
 
@@ -65,7 +224,35 @@ type SearchParams struct {
 	MaxRate     float64 `json:"maxRate"`
 	MinRating   float64 `json:"minRating"`
 	MaxRating   float64 `json:"maxRating"`
-	AmenityMask int     `json:"amenityMask"`
+	AmenityMask int     `json:"amenityMask"` // hotels having all these amenity bits
 	Limit       int     `json:"limit"`
 	Offset      int     `json:"offset"`
+
+	// Hotels having at least one of these amenity bits
+	AnyAmenityMask int `json:"anyAmenityMask,omitempty"`
+
+	// Full-text search over the hotel names, descriptions, addresses and cities, sorted by relevance
+	Query string `json:"q,omitempty"`
+
+	// Result order, overriding the default (relevance for full-text searches, distance for geo searches, else file order)
+	Sort []SortKey `json:"sort,omitempty"`
+
+	// Keyset pagination: the page right after (or before) the cursor, instead of Offset
+	Cursor *SearchCursor `json:"cursor,omitempty"`
+
+	// Count every matching hotel into the page's total, instead of only the ones the page needs
+	CountTotal bool `json:"countTotal,omitempty"`
+
+	// Availability: only hotels free for the nights from CheckIn to CheckOut (YYYY-MM-DD), as reservations see them
+	CheckIn  string `json:"checkIn,omitempty"`
+	CheckOut string `json:"checkOut,omitempty"`
+	RoomType string `json:"roomType,omitempty"` // room type to find free, each hotel's first one when empty like bookings
+
+	// Map viewport: only hotels located inside the box
+	BBox *BoundingBox `json:"bbox,omitempty"`
+
+	// Geo search: hotels near a point, optionally within a radius, sorted by distance
+	Near         *Location `json:"near,omitempty"`
+	Radius       float64   `json:"radius,omitempty"`       // in kilometres, 0 means no limit
+	DistanceUnit string    `json:"distanceUnit,omitempty"` // unit of the computed distances, "KM" or "MI"
 }
@@ -4,43 +4,221 @@ import (
 	"time"
 )
 
-// Reservation represents a hotel reservation
+// Reservation represents a hotel reservation. It holds the nights from StartDate (check-in) up to
+// the night before EndDate (check-out), so another stay can start on its EndDate (see IsOverlapping).
 type Reservation struct {
 	ID           string    `json:"id"`
 	HotelID      string    `json:"hotelId"`
 	CustomerName string    `json:"customerName"`
 	StartDate    string    `json:"startDate"` // ISO 8601 format: YYYY-MM-DD
 	EndDate      string    `json:"endDate"`   // ISO 8601 format: YYYY-MM-DD
+	Nights       int       `json:"nights"`    // number of nights from StartDate to EndDate
+	RoomType     string    `json:"roomType"`  // code of the booked room type
+	Quantity     int       `json:"quantity"`  // number of rooms booked
+	Status       string    `json:"status"`    // lifecycle status, see Transitions
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 }
 
+// Reservation statuses. Reservations start pending; cancelled and no-show ones release their rooms.
+const (
+	StatusPending    = "pending"
+	StatusConfirmed  = "confirmed"
+	StatusCheckedIn  = "checked_in"
+	StatusCheckedOut = "checked_out"
+	StatusCancelled  = "cancelled"
+	StatusNoShow     = "no_show"
+)
+
+// ReservationStatuses lists the statuses of reservations, in lifecycle order
+var ReservationStatuses = []string{
+	StatusPending, StatusConfirmed, StatusCheckedIn, StatusCheckedOut, StatusCancelled, StatusNoShow,
+}
+
+// Reservation actions, moving reservations from a status to the next
+const (
+	ActionConfirm  = "confirm"
+	ActionCancel   = "cancel"
+	ActionCheckIn  = "check-in"
+	ActionCheckOut = "check-out"
+	ActionNoShow   = "no-show"
+)
+
+// Transition is the status change made by a reservation action
+type Transition struct {
+	From []string // statuses the action applies to
+	To   string
+}
+
+// Transitions are the reservation actions by name. Checked out, cancelled and no-show reservations are closed:
+// no action applies to them.
+var Transitions = map[string]Transition{
+	ActionConfirm:  {From: []string{StatusPending}, To: StatusConfirmed},
+	ActionCancel:   {From: []string{StatusPending, StatusConfirmed}, To: StatusCancelled},
+	ActionCheckIn:  {From: []string{StatusConfirmed}, To: StatusCheckedIn},
+	ActionCheckOut: {From: []string{StatusCheckedIn}, To: StatusCheckedOut},
+	ActionNoShow:   {From: []string{StatusConfirmed}, To: StatusNoShow},
+}
+
+// IsReservationStatus reports whether status is a known reservation status
+func IsReservationStatus(status string) bool {
+	for _, known := range ReservationStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatus returns the status a reservation action moves a reservation in the given status to,
+// and false if the action does not apply to that status
+func NextStatus(status, action string) (string, bool) {
+	transition, ok := Transitions[action]
+	if !ok {
+		return "", false
+	}
+	for _, from := range transition.From {
+		if from == status {
+			return transition.To, true
+		}
+	}
+	return "", false
+}
+
+// HoldsRooms reports whether reservations in the given status take their rooms for their nights
+func HoldsRooms(status string) bool {
+	return status != StatusCancelled && status != StatusNoShow
+}
+
+// IsClosed reports whether reservations in the given status can no longer change
+func IsClosed(status string) bool {
+	return status == StatusCheckedOut || status == StatusCancelled || status == StatusNoShow
+}
+
 // ReservationResponse represents the response format for reservation data
 type ReservationResponse struct {
 	Reservations []Reservation `json:"reservations"`
 }
 
-// CreateReservationRequest represents the request body for creating a reservation
+// Night statuses of an availability calendar
+const (
+	NightAvailable = "available" // at least one room can be booked for the night
+	NightBooked    = "booked"    // reservations hold every room for the night
+	NightBlocked   = "blocked"   // rooms are free, but a one-night booking would conflict with adjacent reservations (no same-day turnover only)
+)
+
+// AvailabilityNight is a night of a hotel's availability calendar, from Date to the next day
+type AvailabilityNight struct {
+	Date           string   `json:"date"` // YYYY-MM-DD
+	Status         string   `json:"status"`
+	Remaining      int      `json:"remaining"`                // rooms left for a one-night booking
+	ReservationIDs []string `json:"reservationIds,omitempty"` // the reservations holding rooms that night, or blocking it
+}
+
+// AvailabilityRun is a run of consecutive nights of an availability calendar with the same status, rooms and reservations
+type AvailabilityRun struct {
+	From           string   `json:"from"` // first night, YYYY-MM-DD
+	To             string   `json:"to"`   // day after the last night, YYYY-MM-DD
+	Nights         int      `json:"nights"`
+	Status         string   `json:"status"`
+	Remaining      int      `json:"remaining"`
+	ReservationIDs []string `json:"reservationIds,omitempty"`
+}
+
+// AvailabilityResponse represents the response format for a hotel's availability calendar.
+// It holds either the nights or, in the compact form, their runs.
+type AvailabilityResponse struct {
+	HotelID  string              `json:"hotelId"`
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	RoomType string              `json:"roomType,omitempty"` // only rooms of this type are counted
+	Nights   []AvailabilityNight `json:"nights,omitempty"`
+	Runs     []AvailabilityRun   `json:"runs,omitempty"`
+}
+
+// CompactNights groups consecutive nights with the same status and reservation into runs
+func CompactNights(nights []AvailabilityNight) []AvailabilityRun {
+	runs := []AvailabilityRun{}
+	for _, night := range nights {
+		if last := len(runs) - 1; last >= 0 && runs[last].Status == night.Status && runs[last].Remaining == night.Remaining &&
+			sameStrings(runs[last].ReservationIDs, night.ReservationIDs) {
+			runs[last].Nights++
+			continue
+		}
+		runs = append(runs, AvailabilityRun{
+			From:           night.Date,
+			Nights:         1,
+			Status:         night.Status,
+			Remaining:      night.Remaining,
+			ReservationIDs: night.ReservationIDs,
+		})
+	}
+	for i := range runs {
+		from, _ := ParseDate(runs[i].From)
+		runs[i].To = from.AddDate(0, 0, runs[i].Nights).Format(DateFormat)
+	}
+	return runs
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateReservationRequest represents the request body for creating a reservation.
+// RoomType defaults to the hotel's first room type and Quantity to 1.
 type CreateReservationRequest struct {
 	CustomerName string `json:"customerName"`
 	StartDate    string `json:"startDate"`
 	EndDate      string `json:"endDate"`
+	RoomType     string `json:"roomType,omitempty"`
+	Quantity     int    `json:"quantity,omitempty"`
 }
 
-// UpdateReservationRequest represents the request body for updating a reservation
+// UpdateReservationRequest represents the request body for updating a reservation.
+// RoomType and Quantity keep their current values when omitted.
 type UpdateReservationRequest struct {
 	CustomerName string `json:"customerName"`
 	StartDate    string `json:"startDate"`
 	EndDate      string `json:"endDate"`
+	RoomType     string `json:"roomType,omitempty"`
+	Quantity     int    `json:"quantity,omitempty"`
 }
 
+// DateFormat is the layout of reservation dates, YYYY-MM-DD
+const DateFormat = "2006-01-02"
+
 // ParseDate parses a date string in YYYY-MM-DD format
 func ParseDate(dateStr string) (time.Time, error) {
-	return time.Parse("2006-01-02", dateStr)
+	return time.Parse(DateFormat, dateStr)
 }
 
-// IsOverlapping checks if two date ranges overlap
+// IsOverlapping checks if two stays share a night. Stays are half-open night ranges: a stay from start
+// to end holds the nights of start up to the night before end, so stays ending and starting on the same day
+// do not overlap.
 func IsOverlapping(start1, end1, start2, end2 time.Time) bool {
-	// Two date ranges overlap if one starts before the other ends and ends after the other starts
-	return (start1.Before(end2) || start1.Equal(end2)) && (end1.After(start2) || end1.Equal(start2))
+	// Two stays overlap if each one starts before the other ends
+	return start1.Before(end2) && start2.Before(end1)
+}
+
+// IsConflicting checks if two stays cannot both take the same room. With same-day turnover a room is free
+// again on the check-out day, so only overlapping stays conflict; without it, a stay starting on the day
+// another one ends conflicts as well.
+func IsConflicting(start1, end1, start2, end2 time.Time, sameDayTurnover bool) bool {
+	if sameDayTurnover {
+		return IsOverlapping(start1, end1, start2, end2)
+	}
+	return !start1.After(end2) && !start2.After(end1)
+}
+
+// CountNights returns the number of nights of a stay from start to end
+func CountNights(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24 + 0.5)
 }
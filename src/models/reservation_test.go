@@ -0,0 +1,90 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// day returns midnight UTC of a date in July 2026
+func day(d int) time.Time {
+	return time.Date(2026, 7, d, 0, 0, 0, 0, time.UTC)
+}
+
+// stayCases are pairs of stays, with whether they overlap and whether they conflict without same-day turnover
+var stayCases = []struct {
+	name                       string
+	start1, end1, start2, end2 int
+	overlapping, conflicting   bool
+}{
+	{"same stay", 1, 4, 1, 4, true, true},
+	{"ends on the other's start", 1, 4, 4, 6, false, true},
+	{"starts on the other's end", 4, 6, 1, 4, false, true},
+	{"overlaps the other's start", 1, 5, 4, 8, true, true},
+	{"overlaps the other's end", 4, 8, 1, 5, true, true},
+	{"contains the other", 1, 10, 3, 5, true, true},
+	{"contained in the other", 3, 5, 1, 10, true, true},
+	{"before the other", 1, 3, 5, 7, false, false},
+	{"after the other", 5, 7, 1, 3, false, false},
+	{"one night inside", 3, 4, 1, 5, true, true},
+	{"one night on the other's first night", 1, 2, 1, 5, true, true},
+	{"one night on the other's check-out day", 5, 6, 1, 5, false, true},
+	{"one night ending on the other's start", 0, 1, 1, 5, false, true},
+	{"consecutive one-night stays", 1, 2, 2, 3, false, true},
+	{"one-night stays a night apart", 1, 2, 3, 4, false, false},
+}
+
+func TestIsOverlapping(t *testing.T) {
+	for _, tc := range stayCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsOverlapping(day(tc.start1), day(tc.end1), day(tc.start2), day(tc.end2)); got != tc.overlapping {
+				t.Errorf("IsOverlapping = %v, want %v", got, tc.overlapping)
+			}
+		})
+	}
+}
+
+func TestIsConflicting(t *testing.T) {
+	for _, tc := range stayCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start1, end1, start2, end2 := day(tc.start1), day(tc.end1), day(tc.start2), day(tc.end2)
+			if got := IsConflicting(start1, end1, start2, end2, true); got != tc.overlapping {
+				t.Errorf("IsConflicting with same-day turnover = %v, want %v", got, tc.overlapping)
+			}
+			if got := IsConflicting(start1, end1, start2, end2, false); got != tc.conflicting {
+				t.Errorf("IsConflicting without same-day turnover = %v, want %v", got, tc.conflicting)
+			}
+		})
+	}
+}
+
+func TestCountNights(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	local := func(month time.Month, d int) time.Time {
+		return time.Date(2026, month, d, 0, 0, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       int
+	}{
+		{"one night", day(1), day(2), 1},
+		{"a week", day(1), day(8), 7},
+		{"over a month end", day(30), time.Date(2026, 8, 2, 0, 0, 0, 0, time.UTC), 3},
+		{"over a leap day", time.Date(2028, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2028, 3, 1, 0, 0, 0, 0, time.UTC), 2},
+		{"same day", day(1), day(1), 0},
+		{"over the spring DST change (23 hour day)", local(time.March, 28), local(time.March, 30), 2},
+		{"over the autumn DST change (25 hour day)", local(time.October, 24), local(time.October, 26), 2},
+		{"one night on the autumn DST change", local(time.October, 25), local(time.October, 26), 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := CountNights(tc.start, tc.end); got != tc.want {
+				t.Errorf("CountNights = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package models
+
+// RoomType is a kind of room of a hotel, with the number of rooms of that kind
+type RoomType struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Count int    `json:"count"` // number of rooms, at least 1
+}
+
+// RoomTypeAvailability is a room type with the number of its rooms left for a stay
+type RoomTypeAvailability struct {
+	RoomType
+	Remaining *int `json:"remaining,omitempty"` // only when a stay is given
+}
+
+// RoomTypeResponse represents the response format for the room types of a hotel
+type RoomTypeResponse struct {
+	HotelID   string                 `json:"hotelId"`
+	CheckIn   string                 `json:"checkIn,omitempty"`
+	CheckOut  string                 `json:"checkOut,omitempty"`
+	RoomTypes []RoomTypeAvailability `json:"roomTypes"`
+}
+
+// Property categories, following the EAN propertyCategory convention
+const (
+	PropertyHotel           = 1
+	PropertySuite           = 2
+	PropertyResort          = 3
+	PropertyVacationRental  = 4
+	PropertyBedAndBreakfast = 5
+	PropertyAllInclusive    = 6
+)
+
+// DefaultRoomTypes generates the room types of a hotel that has none configured from its property
+// category, with more premium rooms for better rated hotels. The first room type is booked by default.
+func DefaultRoomTypes(hotel Hotel) []RoomType {
+	// Better rated hotels get more of their premium rooms
+	premium := int(hotel.HotelRating)
+	if premium < 1 {
+		premium = 1
+	}
+
+	switch hotel.PropertyCategory {
+	case PropertySuite:
+		return []RoomType{
+			{Code: "suite", Name: "Suite", Count: 8},
+			{Code: "family_suite", Name: "Family suite", Count: 2 * premium},
+		}
+	case PropertyResort, PropertyAllInclusive:
+		return []RoomType{
+			{Code: "standard", Name: "Standard room", Count: 20},
+			{Code: "sea_view", Name: "Sea view room", Count: 4 * premium},
+			{Code: "villa", Name: "Villa", Count: premium},
+		}
+	case PropertyVacationRental:
+		return []RoomType{
+			{Code: "entire_home", Name: "Entire home", Count: 1},
+		}
+	case PropertyBedAndBreakfast:
+		return []RoomType{
+			{Code: "double", Name: "Double room", Count: 4},
+			{Code: "single", Name: "Single room", Count: 2},
+		}
+	}
+	return []RoomType{
+		{Code: "standard", Name: "Standard room", Count: 10},
+		{Code: "deluxe", Name: "Deluxe room", Count: 2 * premium},
+		{Code: "suite", Name: "Suite", Count: premium},
+	}
+}
+
+// FindRoomType returns the room type with the given code
+func FindRoomType(roomTypes []RoomType, code string) (RoomType, bool) {
+	for _, roomType := range roomTypes {
+		if roomType.Code == code {
+			return roomType, true
+		}
+	}
+	return RoomType{}, false
+}
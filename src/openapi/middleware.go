@@ -0,0 +1,290 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// Mode selects what the Validator checks
+type Mode string
+
+const (
+	// ModeOff disables validation
+	ModeOff Mode = "off"
+	// ModeRequest validates path parameters, query parameters and request bodies
+	ModeRequest Mode = "request"
+	// ModeDev validates requests and also responses, logging responses that do not match the spec
+	ModeDev Mode = "dev"
+	// ModeDevFail validates requests and responses, replacing responses that do not match the spec with a 500
+	ModeDevFail Mode = "dev-fail"
+)
+
+// Modes lists the supported validation modes
+var Modes = []Mode{ModeOff, ModeRequest, ModeDev, ModeDevFail}
+
+// problemContentType is the media type of RFC 7807 problem details
+const problemContentType = "application/problem+json"
+
+// Validator checks requests, and in the dev modes responses, against a Spec
+type Validator struct {
+	spec *Spec
+	mode Mode
+}
+
+// NewValidator creates a Validator for the given spec and mode
+func NewValidator(spec *Spec, mode Mode) *Validator {
+	return &Validator{spec: spec, mode: mode}
+}
+
+// Middleware validates the requests of the operations described in the spec.
+// Requests that do not match get a 400 (or 415) problem+json response listing every mismatch;
+// requests for paths or methods the spec does not describe are passed through untouched.
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	if v.mode == ModeOff {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, pathParams, _ := v.spec.Find(r.Method, r.URL.Path)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if status, mismatches := v.ValidateRequest(r, op, pathParams); len(mismatches) > 0 {
+			writeProblem(w, status, "Request does not match the API specification", mismatches)
+			return
+		}
+
+		if v.mode == ModeRequest {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Buffer the response so it can be checked before it is sent
+		recorder := &responseRecorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if mismatches := v.ValidateResponse(r, op, recorder.status, recorder.header, recorder.body.Bytes()); len(mismatches) > 0 {
+			log.Printf("OpenAPI mismatch: %s %s (%s) answered %d: %s",
+				r.Method, r.URL.Path, op.Path, recorder.status, describe(mismatches))
+			if v.mode == ModeDevFail {
+				writeProblem(w, http.StatusInternalServerError, "Response does not match the API specification", mismatches)
+				return
+			}
+		}
+
+		recorder.flush(w)
+	})
+}
+
+// ValidateRequest checks the parameters and body of a request against an operation.
+// It returns the status code to answer with and every mismatch found; the body is left readable.
+func (v *Validator) ValidateRequest(r *http.Request, op *Operation, pathParams map[string]string) (int, []models.FieldError) {
+	var found problems
+	query := r.URL.Query()
+
+	for _, parameter := range op.Parameters {
+		var values []string
+		switch parameter.In {
+		case "path":
+			if value, ok := pathParams[parameter.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = query[parameter.Name]
+		case "header":
+			values = r.Header.Values(parameter.Name)
+		}
+
+		if len(values) == 0 || (len(values) == 1 && values[0] == "" && parameter.In != "path") {
+			if parameter.Required {
+				found.add(parameter.Name, "is required")
+			}
+			continue
+		}
+		parameter.Schema.validate(parameterValue(parameter, values), parameter.Name, &found)
+	}
+
+	if op.RequestBody == nil {
+		return http.StatusBadRequest, found
+	}
+
+	// Read the body, and put it back for the handler
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		found.add("body", "could not be read")
+		return http.StatusBadRequest, found
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			found.add("body", "is required")
+		}
+		return http.StatusBadRequest, found
+	}
+
+	mediaType, schema, ok := lookupContent(op.RequestBody.Content, r.Header.Get("Content-Type"))
+	if !ok {
+		found.add("Content-Type", "must be one of: "+strings.Join(mediaTypes(op.RequestBody.Content), ", "))
+		return http.StatusUnsupportedMediaType, found
+	}
+	if isJSON(mediaType) {
+		validateJSON(body, schema, &found)
+	}
+
+	return http.StatusBadRequest, found
+}
+
+// ValidateResponse checks a response status, content type and body against an operation.
+// Responses to requests giving a sparse fieldset parameter may leave out required properties.
+func (v *Validator) ValidateResponse(r *http.Request, op *Operation, status int, header http.Header, body []byte) []models.FieldError {
+	var found problems
+
+	response := op.Response(status)
+	if response == nil {
+		found.add("status", fmt.Sprintf("%d is not a documented response", status))
+		return found
+	}
+
+	if len(response.Content) == 0 {
+		if len(bytes.TrimSpace(body)) > 0 {
+			found.add("body", "must be empty")
+		}
+		return found
+	}
+
+	content := response.Content
+	if response.SparseContent != nil && op.sparseRequest(r) {
+		content = response.SparseContent
+	}
+
+	mediaType, schema, ok := lookupContent(content, header.Get("Content-Type"))
+	if !ok {
+		found.add("Content-Type", "must be one of: "+strings.Join(mediaTypes(response.Content), ", "))
+		return found
+	}
+	if isJSON(mediaType) {
+		validateJSON(body, schema, &found)
+	}
+	return found
+}
+
+// sparseRequest reports whether a request gives one of the sparse fieldset parameters of the operation
+func (op *Operation) sparseRequest(r *http.Request) bool {
+	for _, parameter := range op.Parameters {
+		if parameter.SparseFieldset && parameter.In == "query" && r.URL.Query().Get(parameter.Name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateJSON decodes a JSON body and checks it against a schema
+func validateJSON(body []byte, schema *Schema, found *problems) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		found.add("body", "must be valid JSON")
+		return
+	}
+	schema.validate(value, "", found)
+}
+
+// lookupContent finds the schema declared for a Content-Type header, honouring wildcards such as application/*
+func lookupContent(content map[string]*Schema, contentType string) (string, *Schema, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", nil, false
+	}
+	if schema, ok := content[mediaType]; ok {
+		return mediaType, schema, true
+	}
+	if schema, ok := content[mediaType[:strings.Index(mediaType, "/")]+"/*"]; ok {
+		return mediaType, schema, true
+	}
+	if schema, ok := content["*/*"]; ok {
+		return mediaType, schema, true
+	}
+	return "", nil, false
+}
+
+// isJSON reports whether a media type holds JSON, e.g. application/json or application/problem+json
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func mediaTypes(content map[string]*Schema) []string {
+	types := make([]string, 0, len(content))
+	for mediaType := range content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// describe formats mismatches for the log
+func describe(mismatches []models.FieldError) string {
+	parts := make([]string, len(mismatches))
+	for i, mismatch := range mismatches {
+		parts[i] = mismatch.Field + " " + mismatch.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// writeProblem sends an RFC 7807 problem+json response listing the mismatches
+func writeProblem(w http.ResponseWriter, status int, detail string, mismatches []models.FieldError) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Errors: mismatches,
+	})
+}
+
+// responseRecorder buffers a response so it can be validated before being sent
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(data)
+}
+
+// flush sends the buffered response
+func (r *responseRecorder) flush(w http.ResponseWriter) {
+	for name, values := range r.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body.Bytes())
+}
@@ -0,0 +1,640 @@
+// Package openapi loads the OpenAPI 3.0 description of the API and validates
+// requests and responses against it.
+//
+// Only the subset of OpenAPI used by api-spec/openapi.yml is understood; see
+// the "OpenAPI validation" section of the Readme for the supported keywords.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Spec is an OpenAPI document reduced to what the validator needs
+type Spec struct {
+	BasePath   string // path of the first server URL, e.g. "/api"
+	Operations []*Operation
+}
+
+// Operation is a single method on a path of the spec
+type Operation struct {
+	ID          string
+	Method      string // upper case, e.g. "GET"
+	Path        string // path template as written in the spec, e.g. "/hotels/{hotelId}"
+	Parameters  []*Parameter
+	RequestBody *RequestBody
+	Responses   map[string]*Response // by status code, status range (e.g. "4XX") or "default"
+
+	pattern    *regexp.Regexp // compiled Path, with a group per path parameter
+	paramNames []string       // path parameter names, in group order
+	literals   int            // number of literal characters in Path
+}
+
+// Parameter is a path, query or header parameter of an operation
+type Parameter struct {
+	Name     string
+	In       string // "path", "query" or "header"
+	Required bool
+	Explode  bool // repeated values (true) or a comma separated list (false) for arrays
+	Schema   *Schema
+
+	// SparseFieldset marks a parameter limiting the fields of the response (x-sparse-fieldset: true),
+	// whose responses may then leave out required properties
+	SparseFieldset bool
+}
+
+// RequestBody describes the accepted request bodies of an operation
+type RequestBody struct {
+	Required bool
+	Content  map[string]*Schema // media type -> schema, nil schema accepts anything
+}
+
+// Response describes the bodies of a documented response
+type Response struct {
+	Content map[string]*Schema // media type -> schema; empty means no body
+
+	// SparseContent is Content without required properties, for requests giving a sparse fieldset.
+	// It is only set on operations with a SparseFieldset parameter.
+	SparseContent map[string]*Schema
+}
+
+// Schema is a JSON schema as used by OpenAPI 3.0
+type Schema struct {
+	Type     string // empty accepts any type
+	Format   string
+	Nullable bool
+	Enum     []interface{}
+
+	Minimum          *float64
+	Maximum          *float64
+	ExclusiveMinimum bool
+	ExclusiveMaximum bool
+
+	MinLength *int
+	MaxLength *int
+	Pattern   *regexp.Regexp
+
+	Items    *Schema
+	MinItems *int
+	MaxItems *int
+
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties *Schema // schema of properties not listed in Properties
+	NoAdditional         bool    // additionalProperties: false
+
+	AllOf []*Schema
+	AnyOf []*Schema
+	OneOf []*Schema
+}
+
+// Load reads an OpenAPI document from a YAML or JSON file
+func Load(filePath string) (*Spec, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OpenAPI spec: %w", err)
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing OpenAPI spec %s: %w", filePath, err)
+	}
+	return spec, nil
+}
+
+// Parse builds a Spec from a YAML or JSON OpenAPI document
+func Parse(data []byte) (*Spec, error) {
+	var doc interface{}
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	} else {
+		parsed, err := parseYAML(data)
+		if err != nil {
+			return nil, err
+		}
+		doc = parsed
+	}
+
+	root, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("document must be an object")
+	}
+	if version, _ := root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %v, expected 3.x", root["openapi"])
+	}
+
+	l := &loader{root: root, schemas: map[string]*Schema{}}
+	return l.spec()
+}
+
+// Find returns the operation serving the given method and request path, with the path parameter values.
+// pathMatched reports whether the path is described at all, even if not for this method.
+func (s *Spec) Find(method, path string) (op *Operation, params map[string]string, pathMatched bool) {
+	if s.BasePath != "" {
+		if path != s.BasePath && !strings.HasPrefix(path, s.BasePath+"/") {
+			return nil, nil, false
+		}
+		path = strings.TrimPrefix(path, s.BasePath)
+	}
+	for _, candidate := range s.Operations {
+		values, ok := candidate.match(path)
+		if !ok {
+			continue
+		}
+		pathMatched = true
+		if candidate.Method == method {
+			return candidate, values, true
+		}
+	}
+	return nil, nil, pathMatched
+}
+
+// match matches a decoded request path, relative to the base path, against the operation's path template
+func (op *Operation) match(path string) (map[string]string, bool) {
+	groups := op.pattern.FindStringSubmatch(path)
+	if groups == nil {
+		return nil, false
+	}
+	values := make(map[string]string, len(op.paramNames))
+	for i, name := range op.paramNames {
+		values[name] = groups[i+1]
+	}
+	return values, true
+}
+
+// templateParam matches a parameter of a path template, e.g. {hotelId}
+var templateParam = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// compilePath builds the pattern matching a path template such as /hotels/{hotelId}.geojson
+func (op *Operation) compilePath() {
+	expr := "^"
+	last := 0
+	for _, loc := range templateParam.FindAllStringSubmatchIndex(op.Path, -1) {
+		expr += regexp.QuoteMeta(op.Path[last:loc[0]]) + "([^/]+)"
+		op.paramNames = append(op.paramNames, op.Path[loc[2]:loc[3]])
+		op.literals += loc[0] - last
+		last = loc[1]
+	}
+	expr += regexp.QuoteMeta(op.Path[last:]) + "$"
+	op.literals += len(op.Path) - last
+	op.pattern = regexp.MustCompile(expr)
+}
+
+// Response returns the documented response for a status code: the exact code, then its range, then the default
+func (op *Operation) Response(status int) *Response {
+	if response, ok := op.Responses[fmt.Sprint(status)]; ok {
+		return response
+	}
+	if response, ok := op.Responses[fmt.Sprintf("%dXX", status/100)]; ok {
+		return response
+	}
+	return op.Responses["default"]
+}
+
+// loader turns the decoded document into a Spec, resolving local $refs
+type loader struct {
+	root    map[string]interface{}
+	schemas map[string]*Schema // resolved schema $refs, so recursive schemas terminate
+}
+
+// httpMethods are the operation keys of a path item, in the order operations are listed
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+func (l *loader) spec() (*Spec, error) {
+	spec := &Spec{}
+
+	// Use the path of the first server URL as the base path
+	if servers, ok := l.root["servers"].([]interface{}); ok && len(servers) > 0 {
+		if server, ok := servers[0].(map[string]interface{}); ok {
+			if raw, ok := server["url"].(string); ok {
+				parsed, err := url.Parse(raw)
+				if err != nil {
+					return nil, fmt.Errorf("servers[0].url: %w", err)
+				}
+				spec.BasePath = strings.TrimSuffix(parsed.Path, "/")
+			}
+		}
+	}
+
+	paths, _ := l.root["paths"].(map[string]interface{})
+	templates := make([]string, 0, len(paths))
+	for template := range paths {
+		templates = append(templates, template)
+	}
+	sort.Strings(templates)
+
+	for _, template := range templates {
+		item, err := l.object(paths[template], "paths."+template)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := l.parameters(item["parameters"], "paths."+template+".parameters")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, method := range httpMethods {
+			if _, ok := item[method]; !ok {
+				continue
+			}
+			where := "paths." + template + "." + method
+			op, err := l.operation(item[method], where, shared)
+			if err != nil {
+				return nil, err
+			}
+			op.Method = strings.ToUpper(method)
+			op.Path = template
+			op.compilePath()
+			spec.Operations = append(spec.Operations, op)
+		}
+	}
+
+	// Paths with fewer parameters, then with more literal text, are more specific:
+	// /hotels/clusters wins over /hotels/{hotelId}, and /hotels/{hotelId}.geojson too
+	sort.SliceStable(spec.Operations, func(i, j int) bool {
+		a, b := spec.Operations[i], spec.Operations[j]
+		if len(a.paramNames) != len(b.paramNames) {
+			return len(a.paramNames) < len(b.paramNames)
+		}
+		return a.literals > b.literals
+	})
+
+	return spec, nil
+}
+
+func (l *loader) operation(node interface{}, where string, shared []*Parameter) (*Operation, error) {
+	object, err := l.object(node, where)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &Operation{Responses: map[string]*Response{}}
+	op.ID, _ = object["operationId"].(string)
+
+	// Operation parameters override path item parameters with the same name and location
+	own, err := l.parameters(object["parameters"], where+".parameters")
+	if err != nil {
+		return nil, err
+	}
+	for _, parameter := range shared {
+		overridden := false
+		for _, p := range own {
+			overridden = overridden || (p.Name == parameter.Name && p.In == parameter.In)
+		}
+		if !overridden {
+			op.Parameters = append(op.Parameters, parameter)
+		}
+	}
+	op.Parameters = append(op.Parameters, own...)
+
+	if node, ok := object["requestBody"]; ok {
+		body, err := l.object(node, where+".requestBody")
+		if err != nil {
+			return nil, err
+		}
+		required, _ := body["required"].(bool)
+		content, err := l.content(body["content"], where+".requestBody.content")
+		if err != nil {
+			return nil, err
+		}
+		op.RequestBody = &RequestBody{Required: required, Content: content}
+	}
+
+	responses, _ := object["responses"].(map[string]interface{})
+	for status, node := range responses {
+		response, err := l.object(node, where+".responses."+status)
+		if err != nil {
+			return nil, err
+		}
+		content, err := l.content(response["content"], where+".responses."+status+".content")
+		if err != nil {
+			return nil, err
+		}
+		if status != "default" {
+			status = strings.ToUpper(status) // e.g. "4xx" -> "4XX"
+		}
+		op.Responses[status] = &Response{Content: content}
+	}
+
+	// Responses to sparse fieldsets only hold the requested properties
+	for _, parameter := range op.Parameters {
+		if !parameter.SparseFieldset {
+			continue
+		}
+		copies := map[*Schema]*Schema{}
+		for _, response := range op.Responses {
+			response.SparseContent = make(map[string]*Schema, len(response.Content))
+			for mediaType, schema := range response.Content {
+				response.SparseContent[mediaType] = schema.sparse(copies)
+			}
+		}
+		break
+	}
+
+	return op, nil
+}
+
+// sparse copies a schema and the schemas it holds, leaving out the required properties.
+// copies maps the schemas already copied to their copy, so recursive schemas terminate.
+func (s *Schema) sparse(copies map[*Schema]*Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+	if copied, ok := copies[s]; ok {
+		return copied
+	}
+
+	copied := &Schema{}
+	copies[s] = copied
+	*copied = *s
+	copied.Required = nil
+
+	copied.Items = s.Items.sparse(copies)
+	copied.AdditionalProperties = s.AdditionalProperties.sparse(copies)
+	if s.Properties != nil {
+		copied.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			copied.Properties[name] = property.sparse(copies)
+		}
+	}
+	copySchemas := func(schemas []*Schema) []*Schema {
+		if schemas == nil {
+			return nil
+		}
+		list := make([]*Schema, len(schemas))
+		for i, schema := range schemas {
+			list[i] = schema.sparse(copies)
+		}
+		return list
+	}
+	copied.AllOf = copySchemas(s.AllOf)
+	copied.AnyOf = copySchemas(s.AnyOf)
+	copied.OneOf = copySchemas(s.OneOf)
+	return copied
+}
+
+func (l *loader) parameters(node interface{}, where string) ([]*Parameter, error) {
+	if node == nil {
+		return nil, nil
+	}
+	list, ok := node.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a list", where)
+	}
+
+	parameters := make([]*Parameter, 0, len(list))
+	for i, item := range list {
+		at := fmt.Sprintf("%s[%d]", where, i)
+		object, err := l.object(item, at)
+		if err != nil {
+			return nil, err
+		}
+
+		parameter := &Parameter{}
+		parameter.Name, _ = object["name"].(string)
+		parameter.In, _ = object["in"].(string)
+		parameter.Required, _ = object["required"].(bool)
+		parameter.SparseFieldset, _ = object["x-sparse-fieldset"].(bool)
+		if parameter.Name == "" {
+			return nil, fmt.Errorf("%s: name is required", at)
+		}
+		switch parameter.In {
+		case "path", "query", "header":
+		case "cookie":
+			continue
+		default:
+			return nil, fmt.Errorf("%s: unsupported location %q", at, parameter.In)
+		}
+
+		// Form style, the OpenAPI default for query parameters, explodes arrays
+		parameter.Explode = parameter.In == "query"
+		if explode, ok := object["explode"].(bool); ok {
+			parameter.Explode = explode
+		}
+
+		if schema, ok := object["schema"]; ok {
+			if parameter.Schema, err = l.schema(schema, at+".schema"); err != nil {
+				return nil, err
+			}
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters, nil
+}
+
+func (l *loader) content(node interface{}, where string) (map[string]*Schema, error) {
+	content := map[string]*Schema{}
+	media, _ := node.(map[string]interface{})
+	for mediaType, item := range media {
+		object, err := l.object(item, where+"."+mediaType)
+		if err != nil {
+			return nil, err
+		}
+		var schema *Schema
+		if node, ok := object["schema"]; ok {
+			if schema, err = l.schema(node, where+"."+mediaType+".schema"); err != nil {
+				return nil, err
+			}
+		}
+		content[strings.ToLower(mediaType)] = schema
+	}
+	return content, nil
+}
+
+// schema builds a Schema, sharing the result for every use of the same $ref
+func (l *loader) schema(node interface{}, where string) (*Schema, error) {
+	if object, ok := node.(map[string]interface{}); ok {
+		if ref, ok := object["$ref"].(string); ok {
+			if schema, ok := l.schemas[ref]; ok {
+				return schema, nil
+			}
+			// A $ref to another $ref shares the schema the chain leads to
+			target, at, err := l.follow(node, where)
+			if err != nil {
+				return nil, err
+			}
+			if schema, ok := l.schemas[at]; ok {
+				l.schemas[ref] = schema
+				return schema, nil
+			}
+			schema := &Schema{}
+			l.schemas[ref], l.schemas[at] = schema, schema
+			if err := l.fillSchema(schema, target, at); err != nil {
+				return nil, err
+			}
+			return schema, nil
+		}
+	}
+
+	schema := &Schema{}
+	if err := l.fillSchema(schema, node, where); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (l *loader) fillSchema(schema *Schema, node interface{}, where string) error {
+	object, ok := node.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: schema must be an object", where)
+	}
+
+	schema.Type, _ = object["type"].(string)
+	schema.Format, _ = object["format"].(string)
+	schema.Nullable, _ = object["nullable"].(bool)
+	schema.ExclusiveMinimum, _ = object["exclusiveMinimum"].(bool)
+	schema.ExclusiveMaximum, _ = object["exclusiveMaximum"].(bool)
+	schema.Minimum = floatKeyword(object, "minimum")
+	schema.Maximum = floatKeyword(object, "maximum")
+	schema.MinLength = intKeyword(object, "minLength")
+	schema.MaxLength = intKeyword(object, "maxLength")
+	schema.MinItems = intKeyword(object, "minItems")
+	schema.MaxItems = intKeyword(object, "maxItems")
+
+	switch schema.Type {
+	case "", "string", "number", "integer", "boolean", "array", "object":
+	default:
+		return fmt.Errorf("%s: unsupported type %q", where, schema.Type)
+	}
+
+	if enum, ok := object["enum"].([]interface{}); ok {
+		schema.Enum = enum
+	}
+
+	if pattern, ok := object["pattern"].(string); ok {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("%s.pattern: %w", where, err)
+		}
+		schema.Pattern = compiled
+	}
+
+	if items, ok := object["items"]; ok {
+		var err error
+		if schema.Items, err = l.schema(items, where+".items"); err != nil {
+			return err
+		}
+	}
+
+	if properties, ok := object["properties"].(map[string]interface{}); ok {
+		schema.Properties = make(map[string]*Schema, len(properties))
+		for name, property := range properties {
+			var err error
+			if schema.Properties[name], err = l.schema(property, where+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if required, ok := object["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+	switch additional := object["additionalProperties"].(type) {
+	case bool:
+		schema.NoAdditional = !additional
+	case map[string]interface{}:
+		var err error
+		if schema.AdditionalProperties, err = l.schema(additional, where+".additionalProperties"); err != nil {
+			return err
+		}
+	}
+
+	for keyword, target := range map[string]*[]*Schema{"allOf": &schema.AllOf, "anyOf": &schema.AnyOf, "oneOf": &schema.OneOf} {
+		list, ok := object[keyword].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, item := range list {
+			sub, err := l.schema(item, fmt.Sprintf("%s.%s[%d]", where, keyword, i))
+			if err != nil {
+				return err
+			}
+			*target = append(*target, sub)
+		}
+	}
+
+	return nil
+}
+
+// object returns a node as an object, following a $ref if it is one
+func (l *loader) object(node interface{}, where string) (map[string]interface{}, error) {
+	node, where, err := l.follow(node, where)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := node.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an object", where)
+	}
+	return object, nil
+}
+
+// follow returns the node a chain of $refs leads to, and where it is: the last $ref followed, else where.
+// A $ref leading back to itself is an error, as it describes nothing.
+func (l *loader) follow(node interface{}, where string) (interface{}, string, error) {
+	seen := map[string]bool{}
+	for {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return node, where, nil
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return node, where, nil
+		}
+		if seen[ref] {
+			return nil, "", fmt.Errorf("%s: circular $ref %q", where, ref)
+		}
+		seen[ref] = true
+
+		target, err := l.resolve(ref, where)
+		if err != nil {
+			return nil, "", err
+		}
+		node, where = target, ref
+	}
+}
+
+// resolve follows a local JSON pointer reference such as "#/components/schemas/Hotel"
+func (l *loader) resolve(ref, where string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("%s: only local $refs are supported, got %q", where, ref)
+	}
+
+	var node interface{} = l.root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: unresolvable $ref %q", where, ref)
+		}
+		if node, ok = object[token]; !ok {
+			return nil, fmt.Errorf("%s: unresolvable $ref %q", where, ref)
+		}
+	}
+	return node, nil
+}
+
+func floatKeyword(object map[string]interface{}, keyword string) *float64 {
+	if value, ok := object[keyword].(float64); ok {
+		return &value
+	}
+	return nil
+}
+
+func intKeyword(object map[string]interface{}, keyword string) *int {
+	if value, ok := object[keyword].(float64); ok {
+		n := int(value)
+		return &n
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+// testSpec exercises path matching, shared and referenced parameters, response lookup and recursive schemas
+const testSpec = `openapi: 3.0.3
+servers:
+  - url: http://localhost:8080/api/
+paths:
+  /hotels/{hotelId}:
+    parameters:
+      - $ref: '#/components/parameters/HotelId'
+      - name: fields
+        in: query
+        schema: {type: string}
+    get:
+      operationId: getHotel
+      parameters:
+        - name: fields
+          in: query
+          x-sparse-fieldset: true
+          explode: false
+          schema:
+            type: array
+            items: {type: string}
+        - name: session
+          in: cookie
+      responses:
+        '200':
+          description: The hotel
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Hotel'}
+        4XX:
+          $ref: '#/components/responses/Problem'
+        default:
+          description: Anything else
+    delete:
+      requestBody:
+        required: true
+        content:
+          Application/JSON:
+            schema: {$ref: '#/components/schemas/Alias'}
+      responses:
+        '204': {description: Deleted}
+  /hotels/clusters:
+    get:
+      responses:
+        '200': {description: Clusters}
+  /hotels/{hotelId}.geojson:
+    get:
+      responses:
+        '200': {description: GeoJSON}
+components:
+  parameters:
+    HotelId: {name: hotelId, in: path, required: true, schema: {type: string, format: uuid}}
+  responses:
+    Problem:
+      description: A problem
+      content:
+        application/problem+json:
+          schema: {type: object}
+  schemas:
+    Alias: {$ref: '#/components/schemas/Hotel'}
+    Hotel:
+      type: object
+      required: [id, name]
+      properties:
+        id: {type: string}
+        name: {type: string}
+        parent: {$ref: '#/components/schemas/Hotel'}
+        rooms: {type: array, items: {$ref: '#/components/schemas/Alias'}}
+`
+
+// mustParse parses a spec, failing the test on errors
+func mustParse(t *testing.T, document string) *Spec {
+	t.Helper()
+	spec, err := Parse([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestSpecFind(t *testing.T) {
+	spec := mustParse(t, testSpec)
+	if spec.BasePath != "/api" {
+		t.Errorf("BasePath = %q, want /api", spec.BasePath)
+	}
+
+	tests := []struct {
+		method, path string
+		want         string // path of the operation found, empty for none
+		hotelID      string
+		pathMatched  bool
+	}{
+		{"GET", "/api/hotels/clusters", "/hotels/clusters", "", true},
+		{"GET", "/api/hotels/abc", "/hotels/{hotelId}", "abc", true},
+		{"GET", "/api/hotels/abc.geojson", "/hotels/{hotelId}.geojson", "abc", true},
+		{"DELETE", "/api/hotels/abc", "/hotels/{hotelId}", "abc", true},
+		{"POST", "/api/hotels/clusters", "", "", true},
+		{"GET", "/api/hotels/abc/rooms", "", "", false},
+		{"GET", "/hotels/clusters", "", "", false},
+		{"GET", "/apihotels/clusters", "", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			op, params, pathMatched := spec.Find(tc.method, tc.path)
+			if pathMatched != tc.pathMatched {
+				t.Errorf("pathMatched = %v, want %v", pathMatched, tc.pathMatched)
+			}
+			if tc.want == "" {
+				if op != nil {
+					t.Errorf("found %s %s, want none", op.Method, op.Path)
+				}
+				return
+			}
+			if op == nil || op.Path != tc.want || op.Method != tc.method {
+				t.Fatalf("found %+v, want %s %s", op, tc.method, tc.want)
+			}
+			if params["hotelId"] != tc.hotelID {
+				t.Errorf("hotelId = %q, want %q", params["hotelId"], tc.hotelID)
+			}
+		})
+	}
+}
+
+func TestSpecOperation(t *testing.T) {
+	spec := mustParse(t, testSpec)
+	op, _, _ := spec.Find("GET", "/api/hotels/abc")
+	if op.ID != "getHotel" {
+		t.Errorf("ID = %q, want getHotel", op.ID)
+	}
+
+	// The operation's fields parameter overrides the shared one, and cookies are left out
+	if len(op.Parameters) != 2 {
+		t.Fatalf("%d parameters, want 2", len(op.Parameters))
+	}
+	hotelID, fields := op.Parameters[0], op.Parameters[1]
+	if hotelID.Name != "hotelId" || hotelID.In != "path" || !hotelID.Required || hotelID.Schema.Format != "uuid" {
+		t.Errorf("hotelId parameter = %+v", hotelID)
+	}
+	if fields.Name != "fields" || fields.Explode || !fields.SparseFieldset || fields.Schema.Type != "array" {
+		t.Errorf("fields parameter = %+v", fields)
+	}
+
+	// Exact status codes, then ranges, then the default
+	for status, mediaType := range map[int]string{200: "application/json", 404: "application/problem+json", 500: ""} {
+		response := op.Response(status)
+		if response == nil {
+			t.Fatalf("Response(%d) = nil", status)
+		}
+		if _, ok := response.Content[mediaType]; ok != (mediaType != "") || len(response.Content) > 1 {
+			t.Errorf("Response(%d) content = %v, want %q", status, response.Content, mediaType)
+		}
+	}
+	if op.Response(302) != op.Responses["default"] {
+		t.Errorf("Response(302) is not the default response")
+	}
+
+	deleteOp, _, _ := spec.Find("DELETE", "/api/hotels/abc")
+	if deleteOp.RequestBody == nil || !deleteOp.RequestBody.Required {
+		t.Fatalf("RequestBody = %+v, want a required body", deleteOp.RequestBody)
+	}
+	if _, ok := deleteOp.RequestBody.Content["application/json"]; !ok {
+		t.Errorf("media types are not lower-cased: %v", deleteOp.RequestBody.Content)
+	}
+}
+
+func TestSpecRecursiveRefs(t *testing.T) {
+	spec := mustParse(t, testSpec)
+	op, _, _ := spec.Find("GET", "/api/hotels/abc")
+	hotel := op.Response(200).Content["application/json"]
+	if hotel.Properties["parent"] != hotel {
+		t.Errorf("parent is not the Hotel schema itself")
+	}
+	if hotel.Properties["rooms"].Items != hotel {
+		t.Errorf("the Alias schema is not the Hotel schema it refers to")
+	}
+
+	// Recursive schemas validate nested values, and their sparse copies are recursive too
+	value := map[string]interface{}{
+		"id": "a", "name": "A",
+		"parent": map[string]interface{}{"id": "b", "parent": map[string]interface{}{"name": "C"}},
+	}
+	mismatches := hotel.Validate(value, "body")
+	if len(mismatches) != 2 || mismatches[0].Field != "body.parent.name" || mismatches[1].Field != "body.parent.parent.id" {
+		t.Errorf("Validate = %v, want body.parent.name and body.parent.parent.id to be required", mismatches)
+	}
+	sparse := op.Response(200).SparseContent["application/json"]
+	if sparse.Properties["parent"] != sparse || len(sparse.Required) != 0 {
+		t.Errorf("sparse Hotel schema = %+v", sparse)
+	}
+	if mismatches := sparse.Validate(value, "body"); len(mismatches) != 0 {
+		t.Errorf("sparse Validate = %v, want none", mismatches)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	// spec builds a document with an operation using the given parameters and components
+	spec := func(parameters, components string) string {
+		return "openapi: 3.0.0\npaths:\n  /a:\n    get:\n      parameters: " + parameters +
+			"\n      responses:\n        '200':\n          description: ok\ncomponents: " + components
+	}
+	tests := []struct {
+		name     string
+		document string
+		want     string
+	}{
+		{"not an object", "- a", "document must be an object"},
+		{"swagger 2", "swagger: '2.0'", "unsupported openapi version"},
+		{"invalid JSON", `{"openapi": }`, "invalid character"},
+		{"invalid YAML", "openapi: 3.0.0\npaths: [a", "yaml: line 1"},
+		{"unresolvable $ref", spec("[{$ref: '#/components/parameters/Missing'}]", "{}"),
+			`paths./a.get.parameters[0]: unresolvable $ref "#/components/parameters/Missing"`},
+		{"remote $ref", spec("[{$ref: 'other.yml#/Id'}]", "{}"), `only local $refs are supported, got "other.yml#/Id"`},
+		{"circular parameter $ref", spec("[{$ref: '#/components/parameters/A'}]",
+			"{parameters: {A: {$ref: '#/components/parameters/B'}, B: {$ref: '#/components/parameters/A'}}}"),
+			`#/components/parameters/B: circular $ref "#/components/parameters/A"`},
+		{"circular schema $ref", spec("[{name: a, in: query, schema: {$ref: '#/components/schemas/A'}}]",
+			"{schemas: {A: {$ref: '#/components/schemas/A'}}}"), `circular $ref "#/components/schemas/A"`},
+		{"parameters not a list", spec("{name: a}", "{}"), "paths./a.get.parameters: must be a list"},
+		{"parameter without name", spec("[{in: query}]", "{}"), "paths./a.get.parameters[0]: name is required"},
+		{"parameter location", spec("[{name: a, in: body}]", "{}"), `unsupported location "body"`},
+		{"schema type", spec("[{name: a, in: query, schema: {type: date}}]", "{}"), `paths./a.get.parameters[0].schema: unsupported type "date"`},
+		{"schema pattern", spec("[{name: a, in: query, schema: {type: string, pattern: '[a-'}}]", "{}"),
+			"paths./a.get.parameters[0].schema.pattern: error parsing regexp"},
+		{"schema not an object", spec("[{name: a, in: query, schema: string}]", "{}"), "schema must be an object"},
+		{"nested schema error", spec("[{name: a, in: query, schema: {$ref: '#/components/schemas/A'}}]",
+			"{schemas: {A: {properties: {b: {items: {type: map}}}}}}"), `#/components/schemas/A.properties.b.items: unsupported type "map"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.document))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Parse error = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadAPISpec(t *testing.T) {
+	spec, err := Load("../../api-spec/openapi.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.BasePath != "/api" {
+		t.Errorf("BasePath = %q, want /api", spec.BasePath)
+	}
+	op, params, _ := spec.Find("GET", "/api/hotels/0248058a-27e4-11e6-ace6-a9876eff01b3")
+	if op == nil || params["hotelId"] != "0248058a-27e4-11e6-ace6-a9876eff01b3" {
+		t.Errorf("GET /api/hotels/{hotelId} not found: %+v %v", op, params)
+	}
+}
@@ -0,0 +1,292 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// uuidPattern matches the textual form of a UUID
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// problems collects the mismatches found while validating a value
+type problems []models.FieldError
+
+func (p *problems) add(field, message string) {
+	if field == "" {
+		field = "body"
+	}
+	*p = append(*p, models.FieldError{Field: field, Message: message})
+}
+
+// Validate checks a decoded JSON value against the schema, returning every mismatch.
+// Numbers may be float64 or json.Number. field names the value in the reported errors;
+// nested properties are reported as "field.property" and array items as "field[i]".
+func (s *Schema) Validate(value interface{}, field string) []models.FieldError {
+	var found problems
+	s.validate(value, field, &found)
+	return found
+}
+
+func (s *Schema) validate(value interface{}, field string, found *problems) {
+	if s == nil {
+		return
+	}
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			found.add(field, "must not be null")
+		}
+		return
+	}
+
+	for _, sub := range s.AllOf {
+		sub.validate(value, field, found)
+	}
+	if len(s.AnyOf) > 0 && s.matching(s.AnyOf, value) == 0 {
+		found.add(field, "must match at least one of the allowed schemas")
+	}
+	if len(s.OneOf) > 0 && s.matching(s.OneOf, value) != 1 {
+		found.add(field, "must match exactly one of the allowed schemas")
+	}
+
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		found.add(field, "must be one of: "+s.enumList())
+		return
+	}
+
+	switch s.Type {
+	case "string":
+		s.validateString(value, field, found)
+	case "integer", "number":
+		s.validateNumber(value, field, found)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			found.add(field, "must be a boolean")
+		}
+	case "array":
+		s.validateArray(value, field, found)
+	case "object":
+		s.validateObject(value, field, found)
+	}
+}
+
+func (s *Schema) validateString(value interface{}, field string, found *problems) {
+	str, ok := value.(string)
+	if !ok {
+		found.add(field, "must be a string")
+		return
+	}
+
+	length := utf8.RuneCountInString(str)
+	if s.MinLength != nil && length < *s.MinLength {
+		found.add(field, fmt.Sprintf("must be at least %d characters long", *s.MinLength))
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		found.add(field, fmt.Sprintf("must be at most %d characters long", *s.MaxLength))
+	}
+	if s.Pattern != nil && !s.Pattern.MatchString(str) {
+		found.add(field, "must match pattern "+s.Pattern.String())
+	}
+
+	switch s.Format {
+	case "uuid":
+		if !uuidPattern.MatchString(str) {
+			found.add(field, "must be a valid uuid")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", str); err != nil {
+			found.add(field, "must be a date in YYYY-MM-DD format")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			found.add(field, "must be an RFC 3339 date-time")
+		}
+	}
+}
+
+func (s *Schema) validateNumber(value interface{}, field string, found *problems) {
+	number, ok := toFloat(value)
+	if !ok && s.Type == "integer" {
+		found.add(field, "must be an integer")
+		return
+	}
+	if !ok {
+		found.add(field, "must be a number")
+		return
+	}
+	if s.Type == "integer" {
+		if number != math.Trunc(number) {
+			found.add(field, "must be an integer")
+			return
+		}
+		if s.Format == "int32" && (number < math.MinInt32 || number > math.MaxInt32) {
+			found.add(field, "must be a 32-bit integer")
+		}
+	}
+
+	if s.Minimum != nil {
+		if s.ExclusiveMinimum && number <= *s.Minimum {
+			found.add(field, fmt.Sprintf("must be greater than %v", *s.Minimum))
+		} else if number < *s.Minimum {
+			found.add(field, fmt.Sprintf("must be greater than or equal to %v", *s.Minimum))
+		}
+	}
+	if s.Maximum != nil {
+		if s.ExclusiveMaximum && number >= *s.Maximum {
+			found.add(field, fmt.Sprintf("must be less than %v", *s.Maximum))
+		} else if number > *s.Maximum {
+			found.add(field, fmt.Sprintf("must be less than or equal to %v", *s.Maximum))
+		}
+	}
+}
+
+func (s *Schema) validateArray(value interface{}, field string, found *problems) {
+	items, ok := value.([]interface{})
+	if !ok {
+		found.add(field, "must be an array")
+		return
+	}
+	if s.MinItems != nil && len(items) < *s.MinItems {
+		found.add(field, fmt.Sprintf("must have at least %d items", *s.MinItems))
+	}
+	if s.MaxItems != nil && len(items) > *s.MaxItems {
+		found.add(field, fmt.Sprintf("must have at most %d items", *s.MaxItems))
+	}
+	for i, item := range items {
+		s.Items.validate(item, fmt.Sprintf("%s[%d]", field, i), found)
+	}
+}
+
+func (s *Schema) validateObject(value interface{}, field string, found *problems) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		found.add(field, "must be an object")
+		return
+	}
+
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			found.add(childField(field, name), "is required")
+		}
+	}
+
+	// Visit properties in a stable order so errors are reported consistently
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if property, ok := s.Properties[name]; ok {
+			property.validate(object[name], childField(field, name), found)
+		} else if s.AdditionalProperties != nil {
+			s.AdditionalProperties.validate(object[name], childField(field, name), found)
+		} else if s.NoAdditional {
+			found.add(childField(field, name), "unknown field")
+		}
+	}
+}
+
+// matching counts the schemas a value is valid against
+func (s *Schema) matching(schemas []*Schema, value interface{}) int {
+	count := 0
+	for _, sub := range schemas {
+		var found problems
+		sub.validate(value, "", &found)
+		if len(found) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Schema) inEnum(value interface{}) bool {
+	normalized := normalize(value)
+	for _, allowed := range s.Enum {
+		if reflect.DeepEqual(normalize(allowed), normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) enumList() string {
+	values := make([]string, len(s.Enum))
+	for i, value := range s.Enum {
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, ", ")
+}
+
+// normalize converts json.Number values so numbers compare equal however they were decoded
+func normalize(value interface{}) interface{} {
+	if number, ok := toFloat(value); ok {
+		return number
+	}
+	return value
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case json.Number:
+		parsed, err := number.Float64()
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+func childField(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// coerce converts a raw path, query or header value to the JSON type its schema expects.
+// Values that cannot be converted are returned as strings so validation reports them.
+func coerce(raw string, schema *Schema) interface{} {
+	if schema == nil {
+		return raw
+	}
+	switch schema.Type {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(raw, 64); err == nil && !math.IsNaN(number) && !math.IsInf(number, 0) {
+			return json.Number(raw)
+		}
+	case "boolean":
+		switch raw {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	return raw
+}
+
+// parameterValue converts the raw values of a parameter, building an array for array schemas
+func parameterValue(parameter *Parameter, values []string) interface{} {
+	if parameter.Schema == nil || parameter.Schema.Type != "array" {
+		return coerce(values[0], parameter.Schema)
+	}
+	if !parameter.Explode {
+		values = strings.Split(values[0], ",")
+	}
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = coerce(value, parameter.Schema.Items)
+	}
+	return items
+}
@@ -0,0 +1,154 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// parseSchema loads a schema written in YAML
+func parseSchema(t *testing.T, yaml string) *Schema {
+	t.Helper()
+	node, err := parseYAML([]byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &loader{root: map[string]interface{}{}, schemas: map[string]*Schema{}}
+	schema, err := l.schema(node, "schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// TestValidateKeywords checks every supported keyword with a valid and an invalid value, and the errors reported
+func TestValidateKeywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  interface{}
+		want   []string // "field: message" of the expected mismatches
+	}{
+		{"any type", "{}", []interface{}{1.0, "a"}, nil},
+		{"any type null", "{}", nil, nil},
+		{"null", "{type: string}", nil, []string{"v: must not be null"}},
+		{"nullable", "{type: string, nullable: true}", nil, nil},
+
+		{"string", "{type: string}", "a", nil},
+		{"not a string", "{type: string}", 1.0, []string{"v: must be a string"}},
+		{"minLength", "{type: string, minLength: 2}", "é", []string{"v: must be at least 2 characters long"}},
+		{"minLength counts characters", "{type: string, minLength: 2, maxLength: 2}", "éé", nil},
+		{"maxLength", "{type: string, maxLength: 2}", "abc", []string{"v: must be at most 2 characters long"}},
+		{"pattern", "{type: string, pattern: '^[A-Z]{2}$'}", "US", nil},
+		{"pattern mismatch", "{type: string, pattern: '^[A-Z]{2}$'}", "usa", []string{"v: must match pattern ^[A-Z]{2}$"}},
+		{"uuid", "{type: string, format: uuid}", "0248058a-27e4-11e6-ace6-a9876eff01b3", nil},
+		{"not a uuid", "{type: string, format: uuid}", "0248058a-27e4-11e6-ace6", []string{"v: must be a valid uuid"}},
+		{"date", "{type: string, format: date}", "2026-02-28", nil},
+		{"not a date", "{type: string, format: date}", "2026-02-30", []string{"v: must be a date in YYYY-MM-DD format"}},
+		{"date-time", "{type: string, format: date-time}", "2026-02-28T10:00:00+01:00", nil},
+		{"not a date-time", "{type: string, format: date-time}", "2026-02-28 10:00", []string{"v: must be an RFC 3339 date-time"}},
+		{"unknown format", "{type: string, format: email}", "anything", nil},
+
+		{"number", "{type: number}", 1.5, nil},
+		{"json.Number", "{type: number, maximum: 2}", json.Number("1.5"), nil},
+		{"not a number", "{type: number}", "1", []string{"v: must be a number"}},
+		{"integer", "{type: integer}", 3.0, nil},
+		{"not an integer", "{type: integer}", 3.5, []string{"v: must be an integer"}},
+		{"integer string", "{type: integer}", "3", []string{"v: must be an integer"}},
+		{"int32", "{type: integer, format: int32}", 3e9, []string{"v: must be a 32-bit integer"}},
+		{"minimum", "{type: number, minimum: 1}", 1.0, nil},
+		{"below minimum", "{type: number, minimum: 1}", 0.5, []string{"v: must be greater than or equal to 1"}},
+		{"exclusiveMinimum", "{type: number, minimum: 1, exclusiveMinimum: true}", 1.0, []string{"v: must be greater than 1"}},
+		{"maximum", "{type: number, maximum: 5}", 5.0, nil},
+		{"above maximum", "{type: number, maximum: 5}", 5.5, []string{"v: must be less than or equal to 5"}},
+		{"exclusiveMaximum", "{type: number, maximum: 5, exclusiveMaximum: true}", 5.0, []string{"v: must be less than 5"}},
+
+		{"boolean", "{type: boolean}", false, nil},
+		{"not a boolean", "{type: boolean}", "false", []string{"v: must be a boolean"}},
+
+		{"enum", "{type: string, enum: [a, b]}", "b", nil},
+		{"not in enum", "{type: string, enum: [a, b]}", "c", []string{"v: must be one of: a, b"}},
+		{"numeric enum", "{type: integer, enum: [1, 2]}", json.Number("2"), nil},
+		{"nullable enum", "{type: string, nullable: true, enum: [a]}", nil, nil},
+
+		{"array", "{type: array, items: {type: integer}}", []interface{}{1.0, 2.0}, nil},
+		{"not an array", "{type: array}", map[string]interface{}{}, []string{"v: must be an array"}},
+		{"items", "{type: array, items: {type: integer}}", []interface{}{1.0, "2", 3.5},
+			[]string{"v[1]: must be an integer", "v[2]: must be an integer"}},
+		{"minItems", "{type: array, minItems: 1}", []interface{}{}, []string{"v: must have at least 1 items"}},
+		{"maxItems", "{type: array, maxItems: 1}", []interface{}{1.0, 2.0}, []string{"v: must have at most 1 items"}},
+
+		{"object", "{type: object, properties: {a: {type: string}}}", map[string]interface{}{"a": "x", "b": 1.0}, nil},
+		{"not an object", "{type: object}", []interface{}{}, []string{"v: must be an object"}},
+		{"properties", "{type: object, properties: {a: {type: string}, b: {type: integer}}}",
+			map[string]interface{}{"b": "x", "a": 1.0}, []string{"v.a: must be a string", "v.b: must be an integer"}},
+		{"required", "{type: object, required: [a, b], properties: {a: {}}}", map[string]interface{}{"a": nil},
+			[]string{"v.b: is required"}},
+		{"additionalProperties false", "{type: object, additionalProperties: false, properties: {a: {}}}",
+			map[string]interface{}{"a": 1.0, "z": 1.0}, []string{"v.z: unknown field"}},
+		{"additionalProperties schema", "{type: object, additionalProperties: {type: integer}, properties: {a: {}}}",
+			map[string]interface{}{"a": "x", "b": 1.0, "c": "x"}, []string{"v.c: must be an integer"}},
+		{"nested", "{type: object, properties: {a: {type: array, items: {type: object, required: [b]}}}}",
+			map[string]interface{}{"a": []interface{}{map[string]interface{}{}}}, []string{"v.a[0].b: is required"}},
+
+		{"allOf", "{allOf: [{type: object, required: [a]}, {type: object, required: [b]}]}",
+			map[string]interface{}{"a": 1.0}, []string{"v.b: is required"}},
+		{"anyOf", "{anyOf: [{type: string}, {type: integer}]}", 1.0, nil},
+		{"anyOf mismatch", "{anyOf: [{type: string}, {type: integer}]}", true,
+			[]string{"v: must match at least one of the allowed schemas"}},
+		{"oneOf", "{oneOf: [{type: integer}, {type: string}]}", "a", nil},
+		{"oneOf none", "{oneOf: [{type: integer}, {type: string}]}", true,
+			[]string{"v: must match exactly one of the allowed schemas"}},
+		{"oneOf several", "{oneOf: [{type: number}, {type: integer}]}", 1.0,
+			[]string{"v: must match exactly one of the allowed schemas"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, mismatch := range parseSchema(t, tc.schema).Validate(tc.value, "v") {
+				got = append(got, mismatch.Field+": "+mismatch.Message)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Validate = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	schema := parseSchema(t, "{type: object, required: [a]}")
+	mismatches := schema.Validate(map[string]interface{}{}, "")
+	if len(mismatches) != 1 || mismatches[0].Field != "a" {
+		t.Errorf("Validate = %v, want a to be required", mismatches)
+	}
+	if mismatches := schema.Validate("x", ""); len(mismatches) != 1 || mismatches[0].Field != "body" {
+		t.Errorf("Validate = %v, want a mismatch of the body", mismatches)
+	}
+}
+
+func TestParameterValue(t *testing.T) {
+	integers := &Schema{Type: "array", Items: &Schema{Type: "integer"}}
+	tests := []struct {
+		name      string
+		parameter *Parameter
+		values    []string
+		want      interface{}
+	}{
+		{"no schema", &Parameter{}, []string{"x"}, "x"},
+		{"number", &Parameter{Schema: &Schema{Type: "number"}}, []string{"1.5"}, json.Number("1.5")},
+		{"not a number", &Parameter{Schema: &Schema{Type: "integer"}}, []string{"NaN"}, "NaN"},
+		{"boolean", &Parameter{Schema: &Schema{Type: "boolean"}}, []string{"true"}, true},
+		{"not a boolean", &Parameter{Schema: &Schema{Type: "boolean"}}, []string{"yes"}, "yes"},
+		{"exploded array", &Parameter{Explode: true, Schema: integers}, []string{"1", "x"},
+			[]interface{}{json.Number("1"), "x"}},
+		{"comma separated array", &Parameter{Schema: integers}, []string{"1,2"},
+			[]interface{}{json.Number("1"), json.Number("2")}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parameterValue(tc.parameter, tc.values); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parameterValue = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseYAML decodes a YAML document into the values encoding/json decodes to: map[string]interface{},
+// []interface{}, string, float64, bool and nil, so that YAML and JSON specs load the same way
+func parseYAML(data []byte) (interface{}, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	keepText(&root)
+	var doc interface{}
+	if err := root.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return jsonValue(doc)
+}
+
+// keepText retags the mapping keys and timestamps of a document as strings, so that they keep the
+// text they are written with, as in JSON: a 200 response code stays "200" and example: 2026-07-01
+// stays "2026-07-01" instead of becoming a time.Time
+func keepText(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!timestamp" {
+		node.Tag = "!!str"
+	}
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 && child.Kind == yaml.ScalarNode && child.ShortTag() != "!!merge" {
+			child.Tag = "!!str"
+		}
+		keepText(child)
+	}
+}
+
+// jsonValue converts a value decoded by yaml.v3 to its encoding/json equivalent: integers become float64
+func jsonValue(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case nil, string, bool, float64:
+		return value, nil
+	case int:
+		return float64(value), nil
+	case int64:
+		return float64(value), nil
+	case uint64:
+		return float64(value), nil
+	case []interface{}:
+		for i, item := range value {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = converted
+		}
+		return value, nil
+	case map[string]interface{}:
+		for key, item := range value {
+			converted, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = converted
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("unsupported YAML value %v (%T)", value, value)
+	}
+}
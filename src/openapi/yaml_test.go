@@ -0,0 +1,82 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// object is a shorthand for decoded YAML mappings
+type object = map[string]interface{}
+
+// list is a shorthand for decoded YAML sequences
+type list = []interface{}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want interface{}
+	}{
+		{
+			name: "scalars as decoded from JSON",
+			yaml: "int: 1\nnegative: -3\nfloat: 1.5e3\nversion: 0.5.1\nyes: true\nnull: null\n~: tilde\nempty:\nquoted: '1'\ndate: 2026-07-01\ntime: 2026-07-01T10:00:00+02:00",
+			want: object{
+				"int": 1.0, "negative": -3.0, "float": 1500.0, "version": "0.5.1", "yes": true,
+				"null": nil, "~": "tilde", "empty": nil, "quoted": "1", "date": "2026-07-01", "time": "2026-07-01T10:00:00+02:00",
+			},
+		},
+		{
+			name: "keys that are not strings",
+			yaml: "responses:\n  200: ok\n  '404': missing\n  true: yes",
+			want: object{"responses": object{"200": "ok", "404": "missing", "true": "yes"}},
+		},
+		{
+			name: "nested collections",
+			yaml: "a:\n  - b: 1\n    c: [2, x]\n  - {d: 3}",
+			want: object{"a": list{object{"b": 1.0, "c": list{2.0, "x"}}, object{"d": 3.0}}},
+		},
+		{
+			name: "block scalars",
+			yaml: "literal: |\n  a\n  b\nfolded: >-\n  a\n  b",
+			want: object{"literal": "a\nb\n", "folded": "a b"},
+		},
+		{
+			name: "empty document",
+			yaml: "# nothing\n---\n",
+			want: nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(tc.yaml))
+			if err != nil {
+				t.Fatalf("parseYAML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parseYAML = %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"tab indentation", "a:\n\tb: 1", "line 2"},
+		{"duplicate key", "a: 1\nb: 2\na: 3", `mapping key "a" already defined`},
+		{"unterminated flow collection", "a: [x, y\nb: 1", "line 1: did not find expected ',' or ']'"},
+		{"complex key", "? [a, b]\n: c", "invalid map key"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(tc.yaml))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("parseYAML error = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
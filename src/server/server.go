@@ -0,0 +1,248 @@
+// Package server builds the hotels mock API as an embeddable http.Handler.
+//
+// It is meant to be used both by the hotels-api binary and by Go tests that
+// want to run the mock in-process, e.g. with httptest.NewServer:
+//
+//	srv, err := server.New(server.Options{Hotels: fixtures})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	ts := httptest.NewServer(srv)
+//	defer ts.Close()
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/config"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/handlers"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/openapi"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/services"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/utils"
+)
+
+// Options configures a Server
+type Options struct {
+	// Config is the runtime configuration; config.Default() is used when nil
+	Config *config.Config
+
+	// Hotels, when non-nil, are used as in-memory hotel fixtures instead of
+	// loading Config.DataPath
+	Hotels []models.Hotel
+
+	// DisableRequestLogging turns off the request logging middleware
+	DisableRequestLogging bool
+}
+
+// Server is the hotels mock API: its services plus the router serving them
+type Server struct {
+	config             *config.Config
+	initialHotels      []models.Hotel
+	hotelService       *services.HotelService
+	reservationService *services.ReservationService
+	validator          *openapi.Validator // nil when OpenAPI validation is off
+	router             *mux.Router
+}
+
+// New creates a new Server from the given options
+func New(opts Options) (*Server, error) {
+	cfg := opts.Config
+	if cfg == nil {
+		cfg = config.Default()
+	}
+
+	// Create services
+	hotelService := services.NewHotelService()
+
+	// Load hotel data from fixtures or from the JSON file
+	if opts.Hotels != nil {
+		hotelService.SetHotels(opts.Hotels)
+	} else if err := hotelService.LoadHotelsFromFile(cfg.DataPath); err != nil {
+		return nil, fmt.Errorf("failed to load hotel data: %w", err)
+	}
+
+	// Initialize reservation service
+	reservationService := services.NewReservationService(hotelService)
+
+	// Configure the room types of the hotels that have them
+	hotelIDs := make([]string, 0, len(cfg.RoomTypes))
+	for hotelID := range cfg.RoomTypes {
+		hotelIDs = append(hotelIDs, hotelID)
+	}
+	sort.Strings(hotelIDs)
+	for _, hotelID := range hotelIDs {
+		roomTypes := make([]models.RoomType, len(cfg.RoomTypes[hotelID]))
+		for i, roomType := range cfg.RoomTypes[hotelID] {
+			roomTypes[i] = models.RoomType{Code: roomType.Code, Name: roomType.Name, Count: roomType.Count}
+		}
+		if err := reservationService.SetRoomTypes(hotelID, roomTypes); err != nil {
+			return nil, fmt.Errorf("invalid room types for hotel %s: %w", hotelID, err)
+		}
+	}
+
+	// Configure the hotels that set their same-day turnover
+	hotelIDs = hotelIDs[:0]
+	for hotelID := range cfg.SameDayTurnover {
+		hotelIDs = append(hotelIDs, hotelID)
+	}
+	sort.Strings(hotelIDs)
+	for _, hotelID := range hotelIDs {
+		if err := reservationService.SetSameDayTurnover(hotelID, cfg.SameDayTurnover[hotelID]); err != nil {
+			return nil, fmt.Errorf("invalid same-day turnover for hotel %s: %w", hotelID, err)
+		}
+	}
+
+	// Load the OpenAPI spec when validation is enabled
+	var validator *openapi.Validator
+	if mode := openapi.Mode(cfg.OpenAPI.Validation); mode != "" && mode != openapi.ModeOff {
+		spec, err := openapi.Load(cfg.OpenAPI.SpecPath)
+		if err != nil {
+			return nil, err
+		}
+		validator = openapi.NewValidator(spec, mode)
+	}
+
+	s := &Server{
+		config:             cfg,
+		initialHotels:      hotelService.GetHotels(),
+		hotelService:       hotelService,
+		reservationService: reservationService,
+		validator:          validator,
+	}
+	s.router = s.buildRouter(opts)
+
+	return s, nil
+}
+
+// buildRouter creates the router with all middleware and routes registered
+func (s *Server) buildRouter(opts Options) *mux.Router {
+	// Create handlers
+	hotelHandler := handlers.NewHotelHandler(s.hotelService, s.reservationService)
+	hotelHandler.LenientSearch = s.config.LenientSearch
+	reservationHandler := handlers.NewReservationHandler(s.reservationService)
+
+	// Create router, answering unknown routes and methods with problem+json bodies
+	router := mux.NewRouter()
+	router.NotFoundHandler = handlers.NotFoundHandler(router)
+	router.MethodNotAllowedHandler = handlers.MethodNotAllowedHandler(router)
+
+	// Add middleware
+	if !opts.DisableRequestLogging {
+		router.Use(utils.NewLoggingMiddleware(s.config.LogFormat))
+	}
+	router.Use(utils.NewCORSMiddleware(s.config.CORS.AllowedOrigins, s.config.CORS.AllowedMethods, s.config.CORS.AllowedHeaders))
+	if s.validator != nil {
+		router.Use(s.validator.Middleware)
+	}
+
+	// API routes with prefix
+	apiRouter := router.PathPrefix("/api").Subrouter()
+
+	// Register hotel routes (the GeoJSON variants first, so {hotelId} doesn't swallow the suffix)
+	apiRouter.HandleFunc("/hotels.geojson", hotelHandler.GetHotels).Methods("GET")
+	apiRouter.HandleFunc("/hotels/{hotelId}.geojson", hotelHandler.GetHotelByID).Methods("GET")
+	apiRouter.HandleFunc("/hotels", hotelHandler.GetHotels).Methods("GET")
+	apiRouter.HandleFunc("/hotels", hotelHandler.CreateHotel).Methods("POST")
+	apiRouter.HandleFunc("/hotels/clusters", hotelHandler.GetHotelClusters).Methods("GET")
+	apiRouter.HandleFunc("/hotels/facets", hotelHandler.GetHotelFacets).Methods("GET")
+	apiRouter.HandleFunc("/hotels/suggest", hotelHandler.GetHotelSuggestions).Methods("GET")
+	apiRouter.HandleFunc("/hotels/{hotelId}", hotelHandler.GetHotelByID).Methods("GET")
+	apiRouter.HandleFunc("/hotels/{hotelId}", hotelHandler.ReplaceHotel).Methods("PUT")
+	apiRouter.HandleFunc("/hotels/{hotelId}", hotelHandler.PatchHotel).Methods("PATCH")
+	apiRouter.HandleFunc("/hotels/{hotelId}", hotelHandler.DeleteHotel).Methods("DELETE")
+
+	// Register the amenity catalogue
+	apiRouter.HandleFunc("/amenities", hotelHandler.GetAmenities).Methods("GET")
+
+	// Register reservation routes
+	apiRouter.HandleFunc("/hotels/{hotelId}/availability", reservationHandler.GetAvailability).Methods("GET")
+	apiRouter.HandleFunc("/hotels/{hotelId}/rooms", reservationHandler.GetRoomTypes).Methods("GET")
+	apiRouter.HandleFunc("/hotels/{hotelId}/reservations", reservationHandler.GetReservations).Methods("GET")
+	apiRouter.HandleFunc("/hotels/{hotelId}/reservations", reservationHandler.CreateReservation).Methods("POST")
+	apiRouter.HandleFunc("/hotels/{hotelId}/reservations/{reservationId}", reservationHandler.GetReservationByID).Methods("GET")
+	apiRouter.HandleFunc("/hotels/{hotelId}/reservations/{reservationId}", reservationHandler.UpdateReservation).Methods("PUT")
+	apiRouter.HandleFunc("/hotels/{hotelId}/reservations/{reservationId}", reservationHandler.DeleteReservation).Methods("DELETE")
+	for action := range models.Transitions {
+		apiRouter.HandleFunc("/hotels/{hotelId}/reservations/{reservationId}/"+action, reservationHandler.TransitionReservation(action)).Methods("POST")
+	}
+
+	// Serve static assets (e.g. /thumbnails/...) from the public folder
+	if s.config.PublicDir != "" {
+		staticHandler := handlers.NewStaticHandler(s.config.PublicDir)
+		router.MatcherFunc(isNotAPIRequest).Methods("GET", "HEAD").Handler(staticHandler)
+	}
+
+	return router
+}
+
+// isNotAPIRequest keeps unknown /api paths out of the static file server, so they get the API's not found response
+func isNotAPIRequest(r *http.Request, _ *mux.RouteMatch) bool {
+	return r.URL.Path != "/api" && !strings.HasPrefix(r.URL.Path, "/api/")
+}
+
+// ServeHTTP dispatches the request to the API router
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// Handler returns the http.Handler serving the API
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// Config returns the configuration the server was built with
+func (s *Server) Config() *config.Config {
+	return s.config
+}
+
+// HotelService returns the hotel service backing the server
+func (s *Server) HotelService() *services.HotelService {
+	return s.hotelService
+}
+
+// ReservationService returns the reservation service backing the server
+func (s *Server) ReservationService() *services.ReservationService {
+	return s.reservationService
+}
+
+// SeedHotels replaces the current hotels with the given ones.
+// Existing reservations are kept; use Reset to clear them.
+func (s *Server) SeedHotels(hotels []models.Hotel) {
+	s.hotelService.SetHotels(hotels)
+}
+
+// SeedReservations stores the given reservations, stopping at the first invalid one
+func (s *Server) SeedReservations(reservations ...models.Reservation) ([]models.Reservation, error) {
+	seeded := make([]models.Reservation, 0, len(reservations))
+	for _, reservation := range reservations {
+		stored, err := s.reservationService.SeedReservation(reservation)
+		if err != nil {
+			return seeded, fmt.Errorf("failed to seed reservation for hotel %s: %w", reservation.HotelID, err)
+		}
+		seeded = append(seeded, *stored)
+	}
+	return seeded, nil
+}
+
+// Reset restores the hotels the server was created with and removes all reservations
+func (s *Server) Reset() {
+	s.hotelService.SetHotels(s.initialHotels)
+	s.reservationService.Reset()
+}
+
+// HTTPServer returns an http.Server listening on the configured address with the configured timeouts
+func (s *Server) HTTPServer() *http.Server {
+	return &http.Server{
+		Addr:         s.config.Addr,
+		WriteTimeout: time.Duration(s.config.WriteTimeout),
+		ReadTimeout:  time.Duration(s.config.ReadTimeout),
+		IdleTimeout:  time.Duration(s.config.IdleTimeout),
+		Handler:      s,
+	}
+}
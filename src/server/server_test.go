@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/config"
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// testHotel returns a hotel fixture with the given ID and name
+func testHotel(id, name string) models.Hotel {
+	return models.Hotel{
+		ID:               id,
+		Type:             "hotel",
+		Name:             name,
+		City:             "Seattle",
+		CountryCode:      "US",
+		HotelRating:      4,
+		LowRate:          100,
+		HighRate:         200,
+		PropertyCategory: 1,
+		RateCurrencyCode: "USD",
+		Location:         models.Location{Latitude: 47.6, Longitude: -122.3},
+		Metadata:         models.Metadata{Path: "/hotels/" + id},
+	}
+}
+
+// startServer runs a server in-process, failing on responses that do not match the OpenAPI spec.
+// Without hotels, it serves the mock data.
+func startServer(t *testing.T, hotels []models.Hotel) (*Server, *httptest.Server) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.DataPath = "../../mock-data/hotels-data.json"
+	cfg.OpenAPI = config.OpenAPIConfig{SpecPath: "../../api-spec/openapi.yml", Validation: "dev-fail"}
+	srv, err := New(Options{Config: cfg, Hotels: hotels, DisableRequestLogging: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return srv, ts
+}
+
+// getJSON decodes the JSON response of a GET request, failing unless it has the wanted status
+func getJSON(t *testing.T, ts *httptest.Server, path string, status int, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(ts.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("GET %s: status = %d, want %d", path, resp.StatusCode, status)
+	}
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+	}
+}
+
+func TestServerSeedAndReset(t *testing.T) {
+	const (
+		motifID = "0248058a-27e4-11e6-ace6-a9876eff01b3"
+		hyattID = "025fe5c5-27e4-11e6-ace6-a9876eff01b3"
+	)
+	srv, ts := startServer(t, []models.Hotel{})
+
+	var hotels models.HotelResponse
+	getJSON(t, ts, "/api/hotels", http.StatusOK, &hotels)
+	if len(hotels.Hotels) != 0 {
+		t.Fatalf("%d hotels before seeding, want 0", len(hotels.Hotels))
+	}
+
+	srv.SeedHotels([]models.Hotel{testHotel(motifID, "Motif Seattle"), testHotel(hyattID, "Grand Hyatt Seattle")})
+	seeded, err := srv.SeedReservations(models.Reservation{
+		HotelID:      motifID,
+		CustomerName: "Jane Doe",
+		StartDate:    "2026-07-01",
+		EndDate:      "2026-07-04",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seeded) != 1 || seeded[0].ID == "" || seeded[0].CreatedAt.IsZero() {
+		t.Fatalf("SeedReservations = %+v, want a reservation with a generated ID and timestamps", seeded)
+	}
+
+	getJSON(t, ts, "/api/hotels?q=hyatt", http.StatusOK, &hotels)
+	if len(hotels.Hotels) != 1 || hotels.Hotels[0].ID != hyattID {
+		t.Errorf("search after seeding = %+v, want the seeded Grand Hyatt", hotels.Hotels)
+	}
+	var reservation models.Reservation
+	getJSON(t, ts, "/api/hotels/"+motifID+"/reservations/"+seeded[0].ID, http.StatusOK, &reservation)
+	if reservation.CustomerName != "Jane Doe" || reservation.StartDate != "2026-07-01" {
+		t.Errorf("seeded reservation = %+v", reservation)
+	}
+
+	// Seeding stops at the first invalid reservation
+	seeded, err = srv.SeedReservations(
+		models.Reservation{HotelID: hyattID, CustomerName: "A", StartDate: "2026-07-01", EndDate: "2026-07-02"},
+		models.Reservation{HotelID: "missing", CustomerName: "B", StartDate: "2026-07-01", EndDate: "2026-07-02"},
+		models.Reservation{HotelID: hyattID, CustomerName: "C", StartDate: "2026-08-01", EndDate: "2026-08-02"},
+	)
+	if err == nil || !strings.Contains(err.Error(), "hotel missing") || len(seeded) != 1 {
+		t.Errorf("SeedReservations = %d reservations, %v, want 1 and an error for hotel missing", len(seeded), err)
+	}
+
+	srv.Reset()
+	getJSON(t, ts, "/api/hotels", http.StatusOK, &hotels)
+	if len(hotels.Hotels) != 0 {
+		t.Errorf("%d hotels after Reset, want 0", len(hotels.Hotels))
+	}
+	getJSON(t, ts, "/api/hotels/"+motifID+"/reservations", http.StatusNotFound, nil)
+	if reservations, err := srv.ReservationService().GetReservationsByHotelID(motifID); err == nil || len(reservations) != 0 {
+		t.Errorf("reservations after Reset = %v, %v, want none", reservations, err)
+	}
+}
+
+func TestServerResetRestoresFixtures(t *testing.T) {
+	const hotelID = "0248058a-27e4-11e6-ace6-a9876eff01b3"
+	srv, ts := startServer(t, []models.Hotel{testHotel(hotelID, "Motif Seattle")})
+
+	if _, err := srv.SeedReservations(models.Reservation{
+		HotelID: hotelID, CustomerName: "Jane Doe", StartDate: "2026-07-01", EndDate: "2026-07-04",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	srv.SeedHotels(nil)
+	getJSON(t, ts, "/api/hotels/"+hotelID, http.StatusNotFound, nil)
+
+	// The fixtures the server was created with come back, without their reservations
+	srv.Reset()
+	var hotel models.Hotel
+	getJSON(t, ts, "/api/hotels/"+hotelID, http.StatusOK, &hotel)
+	if hotel.Name != "Motif Seattle" {
+		t.Errorf("hotel after Reset = %+v, want the Motif Seattle fixture", hotel)
+	}
+	var reservations models.ReservationResponse
+	getJSON(t, ts, "/api/hotels/"+hotelID+"/reservations", http.StatusOK, &reservations)
+	if len(reservations.Reservations) != 0 {
+		t.Errorf("%d reservations after Reset, want 0", len(reservations.Reservations))
+	}
+}
+
+func TestServerMockDataMatchesSpec(t *testing.T) {
+	_, ts := startServer(t, nil)
+
+	// Hotel IDs are checked as UUIDs, in responses as well as in paths
+	var hotels models.HotelResponse
+	getJSON(t, ts, "/api/hotels?limit=100", http.StatusOK, &hotels)
+	if len(hotels.Hotels) == 0 {
+		t.Fatal("no mock hotels")
+	}
+	for _, hotel := range hotels.Hotels {
+		getJSON(t, ts, "/api/hotels/"+hotel.ID, http.StatusOK, nil)
+	}
+	getJSON(t, ts, "/api/hotels/026efghi-27e4-11e6-afc9-536abd83599e", http.StatusBadRequest, nil)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// AvailabilityChecker reports whether a hotel has a room of a type free for the nights from checkIn to checkOut,
+// of its first room type when roomType is empty. The ReservationService registers one on its HotelService,
+// so searches can filter by availability.
+type AvailabilityChecker func(hotelID string, checkIn, checkOut time.Time, roomType string) bool
+
+// SetAvailabilityChecker sets how availability searches (params.CheckIn/CheckOut/RoomType) check hotels.
+// Without a checker every hotel is available.
+func (s *HotelService) SetAvailabilityChecker(checker AvailabilityChecker) {
+	s.availability.Store(&checker)
+}
+
+// stayDates returns the stay of an availability search, and whether the search has one
+func stayDates(params models.SearchParams) (checkIn, checkOut time.Time, ok bool) {
+	if params.CheckIn == "" || params.CheckOut == "" {
+		return time.Time{}, time.Time{}, false
+	}
+	checkIn, err := models.ParseDate(params.CheckIn)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	checkOut, err = models.ParseDate(params.CheckOut)
+	if err != nil || !checkOut.After(checkIn) {
+		return time.Time{}, time.Time{}, false
+	}
+	return checkIn, checkOut, true
+}
+
+// availableFilter returns the availability filter of a search, or nil when the search has no stay
+func (s *HotelService) availableFilter(params models.SearchParams) func(hotel *models.Hotel) bool {
+	checkIn, checkOut, ok := stayDates(params)
+	if !ok {
+		return nil
+	}
+	checker := s.availability.Load()
+	if checker == nil {
+		return nil
+	}
+	return func(hotel *models.Hotel) bool {
+		return (*checker)(hotel.ID, checkIn, checkOut, params.RoomType)
+	}
+}
+
+// isFree reports whether a hotel has a room of a type left for the given dates, with the same inventory
+// rules as CreateReservation. An empty code checks the first room type, which a booking without one gets;
+// hotels without the room type are never free.
+func (s *ReservationService) isFree(hotelID string, start, end time.Time, code string) bool {
+	// Hotels without reservations have all their rooms free
+	if s.lookup(hotelID) == nil && code == "" {
+		return true
+	}
+	roomTypes, err := s.RoomTypes(hotelID)
+	if err != nil {
+		return false
+	}
+	roomType, ok := roomTypes[0], true
+	if code != "" {
+		if roomType, ok = models.FindRoomType(roomTypes, code); !ok {
+			return false
+		}
+	}
+
+	turnover := s.SameDayTurnover(hotelID)
+	records := s.conflicting(hotelID, start, end, turnover)
+	return roomsLeft(roomType, records, start, end, turnover, "") > 0
+}
+
+// MaxAvailabilityNights is the longest window of an availability calendar, and the longest stay of a reservation
+const MaxAvailabilityNights = 366
+
+// GetAvailability returns the availability calendar of a hotel: one entry per night from the night
+// of from to the night before to, with the rooms left for a one-night booking (of roomType, when given).
+// Nights are booked when reservations hold every room, blocked when rooms are free but a one-night
+// booking would still conflict with adjacent reservations (hotels without same-day turnover only),
+// else available.
+func (s *ReservationService) GetAvailability(hotelID, from, to, roomType string) ([]models.AvailabilityNight, error) {
+	// Check if the hotel exists, and get the room types to count
+	roomTypes, err := s.RoomTypes(hotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate dates and room type
+	problems := &ValidationError{}
+	fromDate, err := models.ParseDate(from)
+	if err != nil {
+		problems.Add("from", "must be a date in YYYY-MM-DD format")
+	}
+	toDate, err := models.ParseDate(to)
+	if err != nil {
+		problems.Add("to", "must be a date in YYYY-MM-DD format")
+	}
+	if len(problems.Fields) == 0 {
+		if !toDate.After(fromDate) {
+			problems.Add("to", "must be after from")
+		} else if toDate.After(fromDate.AddDate(0, 0, MaxAvailabilityNights)) {
+			problems.Add("to", fmt.Sprintf("must be at most %d nights after from", MaxAvailabilityNights))
+		}
+	}
+	if roomType != "" {
+		if selected, ok := models.FindRoomType(roomTypes, roomType); ok {
+			roomTypes = []models.RoomType{selected}
+		} else {
+			problems.Add("roomType", fmt.Sprintf("unknown room type %q", roomType))
+		}
+	}
+	if err := problems.OrNil(); err != nil {
+		return nil, err
+	}
+
+	// Collect the reservations that can book or block a night of the window
+	turnover := s.SameDayTurnover(hotelID)
+	records := s.conflicting(hotelID, fromDate, toDate, turnover)
+	counted := map[string]bool{}
+	for _, roomType := range roomTypes {
+		counted[roomType.Code] = true
+	}
+
+	nights := []models.AvailabilityNight{}
+	for night := fromDate; night.Before(toDate); night = night.AddDate(0, 0, 1) {
+		next := night.AddDate(0, 0, 1)
+		entry := models.AvailabilityNight{Date: night.Format(models.DateFormat)}
+
+		// Rooms left for a one-night booking, and rooms not held by a reservation for the night itself
+		held := 0
+		for _, roomType := range roomTypes {
+			entry.Remaining += roomsLeft(roomType, records, night, next, turnover, "")
+			held += roomType.Count
+		}
+		var holders, blockers []string
+		for _, record := range records {
+			if !counted[record.reservation.RoomType] {
+				continue
+			}
+			if models.IsOverlapping(night, next, record.start, record.end) {
+				holders = append(holders, record.reservation.ID)
+				held -= record.reservation.Quantity
+			}
+			if models.IsConflicting(night, next, record.start, record.end, turnover) {
+				blockers = append(blockers, record.reservation.ID)
+			}
+		}
+
+		switch {
+		case entry.Remaining > 0:
+			entry.Status, entry.ReservationIDs = models.NightAvailable, holders
+		case held <= 0:
+			entry.Status, entry.ReservationIDs = models.NightBooked, holders
+		default:
+			entry.Status, entry.ReservationIDs = models.NightBlocked, blockers
+		}
+		nights = append(nights, entry)
+	}
+	return nights, nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// cowListChunk is the number of positions per chunk of a new cowList. Chunks split once they
+// reach twice that size.
+const cowListChunk = 512
+
+// cowList is a copy-on-write list of hotel positions sorted by a valueOrder, for the immutable hotel
+// snapshots. Its positions are split into chunks, so a write copies the chunk it changes and the
+// list of chunks instead of every position. Published lists are never modified.
+type cowList struct {
+	chunks [][]int // non-empty runs of the sorted positions, in order
+	size   int
+}
+
+// newCowList creates a list of sorted positions. The list takes ownership of the slice.
+func newCowList(sorted []int) *cowList {
+	l := &cowList{chunks: make([][]int, 0, (len(sorted)+cowListChunk-1)/cowListChunk), size: len(sorted)}
+	for from := 0; from < len(sorted); from += cowListChunk {
+		to := from + cowListChunk
+		if to > len(sorted) {
+			to = len(sorted)
+		}
+		l.chunks = append(l.chunks, sorted[from:to:to])
+	}
+	return l
+}
+
+// len returns the number of positions
+func (l *cowList) len() int {
+	return l.size
+}
+
+// search returns the smallest index of the list whose position satisfies pred, or len() if none does.
+// Like sort.Search, it assumes pred is false then true along the list.
+func (l *cowList) search(pred func(i int) bool) int {
+	c := sort.Search(len(l.chunks), func(c int) bool {
+		chunk := l.chunks[c]
+		return pred(chunk[len(chunk)-1])
+	})
+	index := 0
+	for _, chunk := range l.chunks[:c] {
+		index += len(chunk)
+	}
+	if c < len(l.chunks) {
+		chunk := l.chunks[c]
+		index += sort.Search(len(chunk), func(k int) bool { return pred(chunk[k]) })
+	}
+	return index
+}
+
+// slice returns a copy of the positions from index from up to index to
+func (l *cowList) slice(from, to int) []int {
+	result := make([]int, 0, to-from)
+	offset := 0
+	for _, chunk := range l.chunks {
+		if offset >= to {
+			break
+		}
+		lo, hi := from-offset, to-offset
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(chunk) {
+			hi = len(chunk)
+		}
+		if lo < hi {
+			result = append(result, chunk[lo:hi]...)
+		}
+		offset += len(chunk)
+	}
+	return result
+}
+
+// locate returns the chunk where position i, holding hotels[i], belongs in the order, and its index in the chunk
+func (l *cowList) locate(order valueOrder, hotels []*models.Hotel, i int) (c, k int) {
+	c = sort.Search(len(l.chunks), func(c int) bool {
+		chunk := l.chunks[c]
+		return !order.before(hotels, chunk[len(chunk)-1], i)
+	})
+	if c == len(l.chunks) {
+		c--
+	}
+	chunk := l.chunks[c]
+	k = sort.Search(len(chunk), func(k int) bool {
+		return !order.before(hotels, chunk[k], i)
+	})
+	return c, k
+}
+
+// with returns a copy of the list with position i spliced in at its place in the order.
+// hotels holds the hotels of all the positions, including i.
+func (l *cowList) with(order valueOrder, hotels []*models.Hotel, i int) *cowList {
+	if l.size == 0 {
+		return &cowList{chunks: [][]int{{i}}, size: 1}
+	}
+	c, k := l.locate(order, hotels, i)
+	chunk := make([]int, 0, len(l.chunks[c])+1)
+	chunk = append(chunk, l.chunks[c][:k]...)
+	chunk = append(chunk, i)
+	chunk = append(chunk, l.chunks[c][k:]...)
+
+	chunks := make([][]int, 0, len(l.chunks)+1)
+	chunks = append(chunks, l.chunks[:c]...)
+	if half := len(chunk) / 2; len(chunk) >= 2*cowListChunk {
+		chunks = append(chunks, chunk[:half:half], chunk[half:])
+	} else {
+		chunks = append(chunks, chunk)
+	}
+	chunks = append(chunks, l.chunks[c+1:]...)
+	return &cowList{chunks: chunks, size: l.size + 1}
+}
+
+// without returns a copy of the list with position i taken out.
+// hotels holds the hotels the positions were sorted with, including i.
+func (l *cowList) without(order valueOrder, hotels []*models.Hotel, i int) *cowList {
+	c, k := l.locate(order, hotels, i)
+	chunks := make([][]int, 0, len(l.chunks))
+	chunks = append(chunks, l.chunks[:c]...)
+	if len(l.chunks[c]) > 1 {
+		chunk := make([]int, 0, len(l.chunks[c])-1)
+		chunk = append(chunk, l.chunks[c][:k]...)
+		chunks = append(chunks, append(chunk, l.chunks[c][k+1:]...))
+	}
+	chunks = append(chunks, l.chunks[c+1:]...)
+	return &cowList{chunks: chunks, size: l.size - 1}
+}
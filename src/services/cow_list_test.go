@@ -0,0 +1,68 @@
+package services
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// TestCowList checks a list against a sorted slice while it grows past several chunk splits
+// and shrinks until chunks empty, and that the lists it was copied from are left unchanged
+func TestCowList(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	n := 3 * cowListChunk * 2
+	hotels := make([]*models.Hotel, n)
+	for i := range hotels {
+		hotels[i] = &models.Hotel{LowRate: float64(rng.Intn(100))}
+	}
+
+	var want []int
+	l := newCowList(nil)
+	check := func(l *cowList, want []int) {
+		t.Helper()
+		if got := l.slice(0, l.len()); !reflect.DeepEqual(got, append([]int{}, want...)) {
+			t.Fatalf("list of %d positions differs from the sorted slice", len(want))
+		}
+		for _, rate := range []float64{-1, 0, 50, 99, 100} {
+			got := l.search(func(i int) bool { return hotels[i].LowRate >= rate })
+			if k := sort.Search(len(want), func(k int) bool { return hotels[want[k]].LowRate >= rate }); got != k {
+				t.Fatalf("search(LowRate >= %v) = %d, want %d", rate, got, k)
+			}
+		}
+		if got := l.slice(10, 20); len(want) >= 20 && !reflect.DeepEqual(got, want[10:20]) {
+			t.Fatalf("slice(10, 20) = %v, want %v", got, want[10:20])
+		}
+	}
+
+	for _, i := range rng.Perm(n) {
+		previous, previousWant := l, append([]int{}, want...)
+		l = l.with(lowRateOrder, hotels, i)
+		k := sort.Search(len(want), func(k int) bool { return !lowRateOrder.before(hotels, want[k], i) })
+		want = append(want[:k], append([]int{i}, want[k:]...)...)
+		if len(want)%500 == 0 {
+			check(l, want)
+			check(previous, previousWant)
+		}
+	}
+	check(l, want)
+	if len(l.chunks) < 3 {
+		t.Fatalf("%d chunks for %d positions, want splits", len(l.chunks), n)
+	}
+
+	for _, i := range rng.Perm(n) {
+		previous, previousWant := l, append([]int{}, want...)
+		l = l.without(lowRateOrder, hotels, i)
+		k := sort.Search(len(want), func(k int) bool { return !lowRateOrder.before(hotels, want[k], i) })
+		want = append(want[:k], want[k+1:]...)
+		if len(want)%500 == 0 {
+			check(l, want)
+			check(previous, previousWant)
+		}
+	}
+	if l.len() != 0 || len(l.chunks) != 0 {
+		t.Fatalf("%d positions in %d chunks left, want none", l.len(), len(l.chunks))
+	}
+}
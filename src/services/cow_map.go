@@ -0,0 +1,128 @@
+package services
+
+import "sort"
+
+// cowShards is the number of shards of a cowMap
+const cowShards = 256
+
+// cowMap is a copy-on-write hash map for the immutable hotel snapshots. Its entries are spread
+// over shards, so a write copies the shards of the keys it changes instead of the whole map.
+// Published maps are never modified: writes go through a cowMapWriter, which builds a new map.
+type cowMap[K comparable, V any] struct {
+	shards *[cowShards]map[K]V
+	hash   func(key K) uint32
+	size   int
+}
+
+// newCowMap creates an empty map spreading keys over its shards with hash
+func newCowMap[K comparable, V any](hash func(key K) uint32) *cowMap[K, V] {
+	return &cowMap[K, V]{shards: &[cowShards]map[K]V{}, hash: hash}
+}
+
+// get returns the value of a key
+func (m *cowMap[K, V]) get(key K) (V, bool) {
+	value, ok := m.shards[m.hash(key)%cowShards][key]
+	return value, ok
+}
+
+// len returns the number of keys
+func (m *cowMap[K, V]) len() int {
+	return m.size
+}
+
+// each calls fn for every key and value, in no particular order
+func (m *cowMap[K, V]) each(fn func(key K, value V)) {
+	for _, shard := range m.shards {
+		for key, value := range shard {
+			fn(key, value)
+		}
+	}
+}
+
+// writer returns a writer building a modified copy of the map
+func (m *cowMap[K, V]) writer() *cowMapWriter[K, V] {
+	shards := *m.shards
+	return &cowMapWriter[K, V]{m: &cowMap[K, V]{shards: &shards, hash: m.hash, size: m.size}}
+}
+
+// cowMapWriter builds a modified copy of a cowMap, copying each shard the first time one of its keys changes
+type cowMapWriter[K comparable, V any] struct {
+	m     *cowMap[K, V]
+	owned [cowShards]bool // shards already copied for this writer
+}
+
+// get returns the value of a key, including the changes made so far
+func (w *cowMapWriter[K, V]) get(key K) (V, bool) {
+	return w.m.get(key)
+}
+
+// set sets the value of a key
+func (w *cowMapWriter[K, V]) set(key K, value V) {
+	shard := w.own(key)
+	if _, ok := shard[key]; !ok {
+		w.m.size++
+	}
+	shard[key] = value
+}
+
+// delete removes a key
+func (w *cowMapWriter[K, V]) delete(key K) {
+	if _, ok := w.m.get(key); !ok {
+		return
+	}
+	delete(w.own(key), key)
+	w.m.size--
+}
+
+// done returns the new map. The writer must not be used afterwards.
+func (w *cowMapWriter[K, V]) done() *cowMap[K, V] {
+	return w.m
+}
+
+// own returns the shard of a key, copying it first if this writer has not yet
+func (w *cowMapWriter[K, V]) own(key K) map[K]V {
+	i := w.m.hash(key) % cowShards
+	if !w.owned[i] {
+		shard := make(map[K]V, len(w.m.shards[i])+1)
+		for k, v := range w.m.shards[i] {
+			shard[k] = v
+		}
+		w.m.shards[i] = shard
+		w.owned[i] = true
+	}
+	return w.m.shards[i]
+}
+
+// hashString is the FNV-1a hash of a string
+func hashString(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// withPosition returns ascending positions with i added, leaving positions unchanged.
+// Appending past the last position reuses the spare capacity of the backing array (see hotelSnapshot).
+func withPosition(positions []int, i int) []int {
+	if n := len(positions); n == 0 || positions[n-1] < i {
+		return append(positions, i)
+	}
+	k := sort.SearchInts(positions, i)
+	result := make([]int, 0, len(positions)+1)
+	result = append(result, positions[:k]...)
+	result = append(result, i)
+	return append(result, positions[k:]...)
+}
+
+// withoutPosition returns ascending positions with i removed, leaving positions unchanged
+func withoutPosition(positions []int, i int) []int {
+	k := sort.SearchInts(positions, i)
+	if k == len(positions) || positions[k] != i {
+		return positions
+	}
+	result := make([]int, 0, len(positions)-1)
+	result = append(result, positions[:k]...)
+	return append(result, positions[k+1:]...)
+}
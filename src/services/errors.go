@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// Domain errors returned by the services. Handlers map them to HTTP status codes
+// with errors.Is / errors.As, so their messages can change freely.
+var (
+	// ErrHotelNotFound is returned when the requested hotel does not exist
+	ErrHotelNotFound = errors.New("hotel not found")
+
+	// ErrReservationNotFound is returned when the requested reservation does not exist
+	ErrReservationNotFound = errors.New("reservation not found")
+
+	// ErrDateConflict is returned when existing bookings leave too few rooms of the requested type for a reservation's dates
+	ErrDateConflict = errors.New("not enough rooms of the requested type are available for these dates")
+
+	// ErrHotelHasReservations is returned when deleting a hotel that still has reservations without cascading
+	ErrHotelHasReservations = errors.New("hotel has existing reservations")
+
+	// ErrReservationExists is returned when storing a reservation whose ID is already taken
+	ErrReservationExists = errors.New("reservation already exists")
+
+	// ErrInvalidTransition is returned when a reservation action does not apply to the reservation's status
+	ErrInvalidTransition = errors.New("invalid reservation status transition")
+
+	// ErrReservationClosed is returned when updating a checked out, cancelled or no-show reservation
+	ErrReservationClosed = errors.New("reservation is closed")
+)
+
+// ValidationError reports invalid input, with the details of every offending field
+type ValidationError struct {
+	Fields []models.FieldError
+}
+
+// Error returns all field problems as a single message
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Add records a problem with a field
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, models.FieldError{Field: field, Message: message})
+}
+
+// OrNil returns the error if any field problem was recorded, nil otherwise
+func (e *ValidationError) OrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// newValidationError creates a ValidationError for a single field
+func newValidationError(field, message string) *ValidationError {
+	err := &ValidationError{}
+	err.Add(field, message)
+	return err
+}
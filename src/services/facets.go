@@ -0,0 +1,133 @@
+package services
+
+import (
+	"math"
+	"sort"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// facetRanges are the lower bounds of the range buckets of the range facets; the last range is open-ended
+var facetRanges = map[string][]float64{
+	models.FacetLowRate:           {0, 100, 200, 500, 1000},
+	models.FacetTripAdvisorRating: {0, 2, 3, 3.5, 4, 4.5},
+}
+
+// withoutFacetFilter returns the search parameters without the filter on a facet's own field,
+// so selecting a value of a facet doesn't hide its other values (multi-select)
+func withoutFacetFilter(params models.SearchParams, facet string) models.SearchParams {
+	switch facet {
+	case models.FacetCity:
+		params.City = ""
+	case models.FacetCountryCode:
+		params.CountryCode = ""
+	case models.FacetHotelRating:
+		params.MinRating, params.MaxRating = 0, 0
+	case models.FacetAmenities:
+		params.AmenityMask, params.AnyAmenityMask = 0, 0
+	case models.FacetLowRate:
+		params.MinRate, params.MaxRate = 0, 0
+	}
+	return params
+}
+
+// FacetHotels counts the hotels matching the search filters by the values of each requested facet.
+// Each facet is counted with its own filter excluded. It also returns the number of hotels matching all the filters.
+func (s *HotelService) FacetHotels(params models.SearchParams, facets []string) (int, map[string][]models.FacetBucket) {
+	snap := s.snapshot.Load()
+	scores, _ := snap.textScores(params)
+	available := s.availableFilter(params)
+
+	total := 0
+	snap.eachMatch(params, scores, available, func(int, float64, float64) bool {
+		total++
+		return true
+	})
+
+	result := make(map[string][]models.FacetBucket, len(facets))
+	for _, facet := range facets {
+		var hotels []*models.Hotel
+		snap.eachMatch(withoutFacetFilter(params, facet), scores, available, func(i int, _, _ float64) bool {
+			hotels = append(hotels, snap.hotels[i])
+			return true
+		})
+
+		if bounds, ok := facetRanges[facet]; ok {
+			result[facet] = rangeBuckets(hotels, facet, bounds)
+		} else {
+			result[facet] = valueBuckets(hotels, facet)
+		}
+	}
+	return total, result
+}
+
+// valueBuckets counts hotels by facet value. Text values are ordered by decreasing count, numbers by value.
+func valueBuckets(hotels []*models.Hotel, facet string) []models.FacetBucket {
+	counts := map[interface{}]int{}
+	for _, hotel := range hotels {
+		switch facet {
+		case models.FacetCity:
+			counts[hotel.City]++
+		case models.FacetCountryCode:
+			counts[hotel.CountryCode]++
+		case models.FacetHotelRating:
+			counts[hotel.HotelRating]++
+		case models.FacetPropertyCategory:
+			counts[hotel.PropertyCategory]++
+		case models.FacetAmenities:
+			for _, code := range hotel.Amenities {
+				counts[code]++
+			}
+		}
+	}
+
+	buckets := make([]models.FacetBucket, 0, len(counts))
+	for value, count := range counts {
+		buckets = append(buckets, models.FacetBucket{Value: value, Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		x, y := buckets[i], buckets[j]
+		if a, ok := x.Value.(string); ok {
+			if x.Count != y.Count {
+				return x.Count > y.Count
+			}
+			return a < y.Value.(string)
+		}
+		return facetNumber(x.Value) < facetNumber(y.Value)
+	})
+	return buckets
+}
+
+func facetNumber(value interface{}) float64 {
+	switch number := value.(type) {
+	case int:
+		return float64(number)
+	case float64:
+		return number
+	}
+	return math.NaN()
+}
+
+// rangeBuckets counts hotels by range of a numeric facet. Every range is returned, even when empty.
+func rangeBuckets(hotels []*models.Hotel, facet string, bounds []float64) []models.FacetBucket {
+	buckets := make([]models.FacetBucket, len(bounds))
+	for i := range bounds {
+		buckets[i].From = &bounds[i]
+		if i+1 < len(bounds) {
+			buckets[i].To = &bounds[i+1]
+		}
+	}
+
+	for _, hotel := range hotels {
+		value := hotel.LowRate
+		if facet == models.FacetTripAdvisorRating {
+			value = hotel.TripAdvisorRating
+		}
+		// The last range starting at or below the value
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] > value }) - 1
+		if i >= 0 {
+			buckets[i].Count++
+		}
+	}
+	return buckets
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+const (
+	// earthRadiusKm is the mean Earth radius used for great-circle distances
+	earthRadiusKm = 6371.0088
+
+	// kmPerDegreeLat is the length of one degree of latitude
+	kmPerDegreeLat = math.Pi * earthRadiusKm / 180
+
+	// geoCellDegrees is the size of a geoGrid cell (about 28km of latitude)
+	geoCellDegrees = 0.25
+
+	// geoColumns is the number of geoGrid cells around a parallel
+	geoColumns = int(360 / geoCellDegrees)
+)
+
+// distanceKm returns the great-circle (haversine) distance between two points in kilometres
+func distanceKm(a, b models.Location) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// convertDistance converts a distance in kilometres to the given unit, rounded to metres
+func convertDistance(km float64, unit string) float64 {
+	if unit == models.DistanceUnitMi {
+		km /= models.KmPerMile
+	}
+	return math.Round(km*1000) / 1000
+}
+
+// geoCell identifies a cell of a geoGrid
+type geoCell struct {
+	row, column int
+}
+
+// geoGrid is a spatial index bucketing hotel positions into fixed-size latitude/longitude cells
+type geoGrid struct {
+	cells *cowMap[geoCell, []int] // cell -> positions, ascending
+}
+
+// hashCell spreads grid cells over the shards of a cowMap
+func hashCell(cell geoCell) uint32 {
+	return uint32(cell.row)*73856093 ^ uint32(cell.column)*19349663
+}
+
+// cellOf returns the cell containing a location
+func cellOf(location models.Location) geoCell {
+	column := int(math.Floor((location.Longitude + 180) / geoCellDegrees))
+	return geoCell{
+		row:    int(math.Floor((location.Latitude + 90) / geoCellDegrees)),
+		column: ((column % geoColumns) + geoColumns) % geoColumns,
+	}
+}
+
+// newGeoGrid indexes the locations of the given hotels, skipping deleted (nil) ones
+func newGeoGrid(hotels []*models.Hotel) *geoGrid {
+	cells := newCowMap[geoCell, []int](hashCell).writer()
+	for i, hotel := range hotels {
+		if hotel != nil {
+			cell := cellOf(hotel.Location)
+			positions, _ := cells.get(cell)
+			cells.set(cell, append(positions, i))
+		}
+	}
+	return &geoGrid{cells: cells.done()}
+}
+
+// update returns a copy of the grid where the hotel at position i moves from the cell of old to the cell of
+// hotel. Either can be nil, for hotels being added or deleted.
+func (g *geoGrid) update(i int, old, hotel *models.Hotel) *geoGrid {
+	cells := g.cells.writer()
+	if old != nil {
+		cell := cellOf(old.Location)
+		positions, _ := cells.get(cell)
+		if positions = withoutPosition(positions, i); len(positions) == 0 {
+			cells.delete(cell)
+		} else {
+			cells.set(cell, positions)
+		}
+	}
+	if hotel != nil {
+		cell := cellOf(hotel.Location)
+		positions, _ := cells.get(cell)
+		cells.set(cell, withPosition(positions, i))
+	}
+	return &geoGrid{cells: cells.done()}
+}
+
+// within returns the positions of the hotels in the cells that may hold points within radiusKm
+// of center, in no particular order. Callers must still check the exact distance.
+// ok is false when the circle covers too many cells to be worth using the index.
+func (g *geoGrid) within(center models.Location, radiusKm float64) (positions []int, ok bool) {
+	latSpan := radiusKm / kmPerDegreeLat
+	minLat, maxLat := center.Latitude-latSpan, center.Latitude+latSpan
+
+	// Circles reaching a pole span every longitude
+	cosLat := math.Min(math.Cos(minLat*math.Pi/180), math.Cos(maxLat*math.Pi/180))
+	if minLat <= -90 || maxLat >= 90 || cosLat <= 0 {
+		return nil, false
+	}
+	lngSpan := latSpan / cosLat
+	if lngSpan >= 180 {
+		return nil, false
+	}
+
+	return g.inBox(models.BoundingBox{
+		MinLatitude:  minLat,
+		MinLongitude: normalizeLongitude(center.Longitude - lngSpan),
+		MaxLatitude:  maxLat,
+		MaxLongitude: normalizeLongitude(center.Longitude + lngSpan),
+	}), true
+}
+
+// inBox returns the positions of the hotels in the cells overlapping a bounding box,
+// in no particular order. Callers must still check that each location is inside the box.
+func (g *geoGrid) inBox(box models.BoundingBox) []int {
+	from := cellOf(models.Location{Latitude: box.MinLatitude, Longitude: box.MinLongitude})
+	to := cellOf(models.Location{Latitude: box.MaxLatitude, Longitude: box.MaxLongitude})
+
+	// Boxes crossing the antimeridian wrap around to the first columns
+	width := box.MaxLongitude - box.MinLongitude
+	if width < 0 {
+		width += 360
+	}
+	columns := geoColumns
+	if width < 360-geoCellDegrees {
+		columns = (to.column-from.column+geoColumns)%geoColumns + 1
+	}
+	inColumns := func(column int) bool {
+		return (column-from.column+geoColumns)%geoColumns < columns
+	}
+
+	var positions []int
+	if (to.row-from.row+1)*columns > g.cells.len() {
+		// Visiting every occupied cell is cheaper
+		g.cells.each(func(cell geoCell, cellPositions []int) {
+			if cell.row >= from.row && cell.row <= to.row && inColumns(cell.column) {
+				positions = append(positions, cellPositions...)
+			}
+		})
+		return positions
+	}
+
+	for row := from.row; row <= to.row; row++ {
+		for i := 0; i < columns; i++ {
+			cell := geoCell{row: row, column: (from.column + i) % geoColumns}
+			cellPositions, _ := g.cells.get(cell)
+			positions = append(positions, cellPositions...)
+		}
+	}
+	return positions
+}
+
+// normalizeLongitude wraps a longitude into [-180, 180)
+func normalizeLongitude(longitude float64) float64 {
+	return math.Mod(math.Mod(longitude+180, 360)+360, 360) - 180
+}
+
+// clusterCellDegrees returns the size of the cluster grid cells for a map zoom level:
+// a quarter of a map tile, so zooming in by one level splits every cell in four
+func clusterCellDegrees(zoom int) float64 {
+	return 360 / math.Pow(2, float64(zoom)) / 4
+}
+
+// ClusterHotels groups the hotels matching the search filters into grid clusters for a map zoom level.
+// Pagination and geo search parameters are ignored. Clusters are ordered by decreasing size.
+func (s *HotelService) ClusterHotels(params models.SearchParams, zoom int) []models.HotelCluster {
+	snap := s.snapshot.Load()
+	cellDegrees := clusterCellDegrees(zoom)
+
+	type accumulator struct {
+		cell       geoCell
+		count      int
+		latSum     float64
+		lngSum     float64
+		minLowRate float64
+		maxLowRate float64
+		hotelID    string
+	}
+	clusters := map[geoCell]*accumulator{}
+
+	// Add every matching hotel to the cluster of its cell
+	scores, _ := snap.textScores(params)
+	snap.eachMatch(params, scores, s.availableFilter(params), func(i int, _, _ float64) bool {
+		hotel := snap.hotels[i]
+		cell := geoCell{
+			row:    int(math.Floor((hotel.Location.Latitude + 90) / cellDegrees)),
+			column: int(math.Floor((normalizeLongitude(hotel.Location.Longitude) + 180) / cellDegrees)),
+		}
+		acc, ok := clusters[cell]
+		if !ok {
+			acc = &accumulator{cell: cell, minLowRate: hotel.LowRate, maxLowRate: hotel.LowRate, hotelID: hotel.ID}
+			clusters[cell] = acc
+		}
+		acc.count++
+		acc.latSum += hotel.Location.Latitude
+		acc.lngSum += hotel.Location.Longitude
+		acc.minLowRate = math.Min(acc.minLowRate, hotel.LowRate)
+		acc.maxLowRate = math.Max(acc.maxLowRate, hotel.LowRate)
+		return true
+	})
+
+	result := make([]models.HotelCluster, 0, len(clusters))
+	for _, acc := range clusters {
+		cluster := models.HotelCluster{
+			ID:    fmt.Sprintf("%d/%d/%d", zoom, acc.cell.row, acc.cell.column),
+			Count: acc.count,
+			Centroid: models.Location{
+				Latitude:  acc.latSum / float64(acc.count),
+				Longitude: acc.lngSum / float64(acc.count),
+			},
+			Bounds: models.BoundingBox{
+				MinLatitude:  float64(acc.cell.row)*cellDegrees - 90,
+				MinLongitude: float64(acc.cell.column)*cellDegrees - 180,
+				MaxLatitude:  math.Min(90, float64(acc.cell.row+1)*cellDegrees-90),
+				MaxLongitude: math.Min(180, float64(acc.cell.column+1)*cellDegrees-180),
+			},
+			MinLowRate: acc.minLowRate,
+			MaxLowRate: acc.maxLowRate,
+		}
+		if acc.count == 1 {
+			cluster.HotelID = acc.hotelID
+		}
+		result = append(result, cluster)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// hotelSnapshot is an immutable, indexed view of the hotel data.
+// Writers build a new snapshot and publish it atomically, so readers never need a lock.
+// Hotels keep their position while they are stored, so a write only updates the index
+// entries of the hotel it changes (see update); deleted hotels leave a nil position behind
+// until the snapshot is compacted.
+//
+// Appending to a slice of a published snapshot may write to the spare capacity of its backing
+// array, which is safe: a published snapshot only reads up to its own slice lengths, and writers
+// are serialized, so no two appends claim the same spare slot.
+type hotelSnapshot struct {
+	hotels    []*models.Hotel        // hotels by position in insertion order, nil once deleted; never mutated once published
+	seqs      []int64                // insertion sequence of each position, ascending and never reused
+	count     int                    // number of hotels, i.e. of non-nil positions
+	byID      *cowMap[string, int]   // hotel ID -> position in hotels
+	names     []string               // folded hotel names by position, for sorting
+	byCity    *cowMap[string, []int] // lower-cased city -> positions, ascending
+	byCountry *cowMap[string, []int] // country code -> positions, ascending
+	byLowRate *cowList               // positions sorted by LowRate
+	byRating  *cowList               // positions sorted by HotelRating
+	geo       *geoGrid               // positions bucketed by location
+	text      *textIndex             // full-text index of the hotel text fields
+	suggest   *suggestIndex          // autocomplete trie of hotel names, cities and landmarks
+}
+
+// newHotelSnapshot builds a snapshot and all its indexes for the given hotels and their insertion sequences.
+// The snapshot takes ownership of the slices and of the hotels they point to.
+func newHotelSnapshot(hotels []*models.Hotel, seqs []int64) *hotelSnapshot {
+	snap := &hotelSnapshot{
+		hotels:  hotels,
+		seqs:    seqs,
+		count:   len(hotels),
+		names:   make([]string, len(hotels)),
+		geo:     newGeoGrid(hotels),
+		text:    newTextIndex(hotels),
+		suggest: newSuggestIndex(hotels),
+	}
+
+	byID := newCowMap[string, int](hashString).writer()
+	byCity := newCowMap[string, []int](hashString).writer()
+	byCountry := newCowMap[string, []int](hashString).writer()
+	byLowRate := make([]int, len(hotels))
+	byRating := make([]int, len(hotels))
+	for i, hotel := range hotels {
+		byID.set(hotel.ID, i)
+		snap.names[i] = foldText(hotel.Name)
+		city := strings.ToLower(hotel.City)
+		positions, _ := byCity.get(city)
+		byCity.set(city, append(positions, i))
+		positions, _ = byCountry.get(hotel.CountryCode)
+		byCountry.set(hotel.CountryCode, append(positions, i))
+		byLowRate[i] = i
+		byRating[i] = i
+	}
+	snap.byID, snap.byCity, snap.byCountry = byID.done(), byCity.done(), byCountry.done()
+
+	// Ties keep insertion order
+	sort.Slice(byLowRate, func(a, b int) bool {
+		return lowRateOrder.before(hotels, byLowRate[a], byLowRate[b])
+	})
+	sort.Slice(byRating, func(a, b int) bool {
+		return ratingOrder.before(hotels, byRating[a], byRating[b])
+	})
+	snap.byLowRate, snap.byRating = newCowList(byLowRate), newCowList(byRating)
+
+	return snap
+}
+
+// update returns a copy of the snapshot where hotel is stored at position i, with insertion sequence seq:
+// a new hotel when i is past the last position, else a replacement of the hotel at i (keeping its sequence),
+// or its deletion when hotel is nil. Only the index entries of the changed hotel are copied.
+func (snap *hotelSnapshot) update(i int, hotel *models.Hotel, seq int64) *hotelSnapshot {
+	next := &hotelSnapshot{count: snap.count}
+
+	// Store the hotel at its position. New hotels are appended in place (see hotelSnapshot).
+	var old *models.Hotel
+	if i == len(snap.hotels) {
+		next.hotels = append(snap.hotels, hotel)
+		next.seqs = append(snap.seqs, seq)
+		next.names = append(snap.names, foldText(hotel.Name))
+	} else {
+		old = snap.hotels[i]
+		next.hotels = append([]*models.Hotel{}, snap.hotels...)
+		next.hotels[i] = hotel
+		next.seqs = snap.seqs
+		next.names = append([]string{}, snap.names...)
+		next.names[i] = ""
+		if hotel != nil {
+			next.names[i] = foldText(hotel.Name)
+		}
+	}
+
+	// Move the index entries of the hotel from its old values to its new ones
+	byID := snap.byID.writer()
+	byCity := snap.byCity.writer()
+	byCountry := snap.byCountry.writer()
+	next.byLowRate, next.byRating = snap.byLowRate, snap.byRating
+	if old != nil {
+		next.count--
+		byID.delete(old.ID)
+		removePosition(byCity, strings.ToLower(old.City), i)
+		removePosition(byCountry, old.CountryCode, i)
+		next.byLowRate = next.byLowRate.without(lowRateOrder, snap.hotels, i)
+		next.byRating = next.byRating.without(ratingOrder, snap.hotels, i)
+	}
+	if hotel != nil {
+		next.count++
+		byID.set(hotel.ID, i)
+		addPosition(byCity, strings.ToLower(hotel.City), i)
+		addPosition(byCountry, hotel.CountryCode, i)
+		next.byLowRate = next.byLowRate.with(lowRateOrder, next.hotels, i)
+		next.byRating = next.byRating.with(ratingOrder, next.hotels, i)
+	}
+	next.byID, next.byCity, next.byCountry = byID.done(), byCity.done(), byCountry.done()
+	next.geo = snap.geo.update(i, old, hotel)
+	next.text = snap.text.update(i, old, hotel)
+	next.suggest = snap.suggest.update(old, hotel)
+
+	return next
+}
+
+// compact returns a snapshot without the positions of deleted hotels once they outnumber the stored ones,
+// else the snapshot itself. Positions change, but insertion sequences are kept.
+func (snap *hotelSnapshot) compact() *hotelSnapshot {
+	if len(snap.hotels)-snap.count <= snap.count {
+		return snap
+	}
+	hotels := make([]*models.Hotel, 0, snap.count)
+	seqs := make([]int64, 0, snap.count)
+	for i, hotel := range snap.hotels {
+		if hotel != nil {
+			hotels = append(hotels, hotel)
+			seqs = append(seqs, snap.seqs[i])
+		}
+	}
+	return newHotelSnapshot(hotels, seqs)
+}
+
+// addPosition adds position i to the positions of a key
+func addPosition(index *cowMapWriter[string, []int], key string, i int) {
+	positions, _ := index.get(key)
+	index.set(key, withPosition(positions, i))
+}
+
+// removePosition removes position i from the positions of a key, and the key once it has none left
+func removePosition(index *cowMapWriter[string, []int], key string, i int) {
+	positions, _ := index.get(key)
+	if positions = withoutPosition(positions, i); len(positions) == 0 {
+		index.delete(key)
+	} else {
+		index.set(key, positions)
+	}
+}
+
+// valueOrder orders hotel positions by a hotel value, ties in position (insertion) order
+type valueOrder func(hotel *models.Hotel) float64
+
+var (
+	lowRateOrder valueOrder = func(hotel *models.Hotel) float64 { return hotel.LowRate }
+	ratingOrder  valueOrder = func(hotel *models.Hotel) float64 { return hotel.HotelRating }
+)
+
+// before reports whether position a comes before position b
+func (order valueOrder) before(hotels []*models.Hotel, a, b int) bool {
+	x, y := order(hotels[a]), order(hotels[b])
+	if x != y {
+		return x < y
+	}
+	return a < b
+}
+
+// textScores returns the relevance of the hotels matching the full-text query by position, and the corpus
+// statistics it was computed with (kept from the cursor, if any), or nil when the search has no query
+func (snap *hotelSnapshot) textScores(params models.SearchParams) (map[int]float64, *models.TextCorpus) {
+	if params.Query == "" {
+		return nil, nil
+	}
+	var corpus *models.TextCorpus
+	if params.Cursor != nil {
+		corpus = params.Cursor.Corpus
+	}
+	return snap.text.search(params.Query, corpus)
+}
+
+// eachMatch calls fn with the position of every hotel matching the search filters, in insertion order,
+// along with its relevance to the full-text query and its distance to the near point when the search has them.
+// scores are the full-text matches from textScores, and available the availability filter, if any.
+// Availability is checked last, as it looks up the hotel's reservations. Iteration stops when fn returns false.
+func (snap *hotelSnapshot) eachMatch(params models.SearchParams, scores map[int]float64, available func(hotel *models.Hotel) bool, fn func(i int, score, km float64) bool) {
+	// match applies the filters to the hotel at position i, reporting whether to go on
+	match := func(i int) bool {
+		hotel := snap.hotels[i]
+		if hotel == nil || !matchesSearchParams(hotel, params) {
+			return true
+		}
+		var score, km float64
+		if scores != nil {
+			var ok bool
+			if score, ok = scores[i]; !ok {
+				return true
+			}
+		}
+		if params.Near != nil {
+			km = distanceKm(*params.Near, hotel.Location)
+			if params.Radius > 0 && km > params.Radius {
+				return true
+			}
+		}
+		if available != nil && !available(hotel) {
+			return true
+		}
+		return fn(i, score, km)
+	}
+
+	// Apply filters to the candidates selected by the indexes
+	if candidates := snap.candidates(params, scores); candidates == nil {
+		for i := range snap.hotels {
+			if !match(i) {
+				return
+			}
+		}
+	} else {
+		for _, i := range candidates {
+			if !match(i) {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns the positions of the hotels that may match the search parameters,
+// in ascending order, using the most selective index available.
+// scores are the full-text matches from textScores, if any.
+// A nil result means every hotel is a candidate. Callers must still apply the full filter.
+func (snap *hotelSnapshot) candidates(params models.SearchParams, scores map[int]float64) []int {
+	// Only the most selective index has its positions listed, so each is considered with its size
+	var best func() []int
+	size := 0
+	sorted := true
+	consider := func(n int, positions func() []int, ascending bool) {
+		if best == nil || n < size {
+			best, size, sorted = positions, n, ascending
+		}
+	}
+	listed := func(positions []int) func() []int {
+		return func() []int { return positions }
+	}
+
+	if scores != nil {
+		consider(len(scores), func() []int {
+			positions := make([]int, 0, len(scores))
+			for i := range scores {
+				positions = append(positions, i)
+			}
+			sort.Ints(positions)
+			return positions
+		}, true)
+	}
+
+	if params.City != "" {
+		positions, _ := snap.byCity.get(strings.ToLower(params.City))
+		consider(len(positions), listed(positions), true)
+	}
+
+	if params.CountryCode != "" {
+		positions, _ := snap.byCountry.get(params.CountryCode)
+		consider(len(positions), listed(positions), true)
+	}
+
+	if params.MinRate > 0 {
+		from := snap.byLowRate.search(func(i int) bool {
+			return snap.hotels[i].LowRate >= params.MinRate
+		})
+		to := snap.byLowRate.len()
+		consider(to-from, func() []int { return snap.byLowRate.slice(from, to) }, false)
+	}
+
+	if params.MinRating > 0 || params.MaxRating > 0 {
+		from, to := 0, snap.byRating.len()
+		if params.MinRating > 0 {
+			from = snap.byRating.search(func(i int) bool {
+				return snap.hotels[i].HotelRating >= params.MinRating
+			})
+		}
+		if params.MaxRating > 0 {
+			to = snap.byRating.search(func(i int) bool {
+				return snap.hotels[i].HotelRating > params.MaxRating
+			})
+		}
+		if to < from {
+			to = from
+		}
+		consider(to-from, func() []int { return snap.byRating.slice(from, to) }, false)
+	}
+
+	if params.BBox != nil {
+		positions := snap.geo.inBox(*params.BBox)
+		consider(len(positions), listed(positions), false)
+	}
+
+	if params.Near != nil && params.Radius > 0 {
+		if positions, ok := snap.geo.within(*params.Near, params.Radius); ok {
+			consider(len(positions), listed(positions), false)
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	positions := best()
+	if positions == nil {
+		positions = []int{}
+	}
+	if sorted {
+		return positions
+	}
+
+	// Restore insertion order for positions taken from a value-sorted index.
+	// Positions listed by an index are never modified, so sort a copy.
+	positions = append([]int{}, positions...)
+	sort.Ints(positions)
+	return positions
+}
@@ -1,26 +1,42 @@
 package services
 
 import (
+	"bytes"
+	"container/heap"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
 )
 
-// HotelService handles hotel data operations
+// HotelService handles hotel data operations.
+//
+// Hotels are kept in immutable, indexed snapshots (see hotelSnapshot). Reads load
+// the current snapshot without locking; writes are serialized, build a new snapshot
+// (copy-on-write, copying only the index entries they change) and publish it atomically,
+// so searches never block writers.
 type HotelService struct {
-	Hotels []models.Hotel
+	snapshot atomic.Pointer[hotelSnapshot]
+	mutex    sync.Mutex // serializes writers
+	lastSeq  int64      // insertion sequence of the last stored hotel, guarded by mutex
+
+	availability atomic.Pointer[AvailabilityChecker] // checks availability searches, see SetAvailabilityChecker
 }
 
 // NewHotelService creates a new instance of HotelService
 func NewHotelService() *HotelService {
-	return &HotelService{
-		Hotels: []models.Hotel{},
-	}
+	s := &HotelService{}
+	s.snapshot.Store(newHotelSnapshot([]*models.Hotel{}, []int64{}))
+	return s
 }
 
 // LoadHotelsFromFile loads hotel data from the specified JSON file
@@ -44,90 +60,565 @@ func (s *HotelService) LoadHotelsFromFile(filePath string) error {
 	}
 
 	// Store hotels
-	s.Hotels = response.Hotels
+	s.SetHotels(response.Hotels)
 	return nil
 }
 
+// SetHotels replaces the hotel data with the given hotels
+func (s *HotelService) SetHotels(hotels []models.Hotel) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := make([]*models.Hotel, len(hotels))
+	seqs := make([]int64, len(hotels))
+	for i := range hotels {
+		hotel := hotels[i]
+		hotel.Amenities = models.AmenityCodes(hotel.AmenityMask)
+		stored[i] = &hotel
+		s.lastSeq++
+		seqs[i] = s.lastSeq
+	}
+	s.snapshot.Store(newHotelSnapshot(stored, seqs))
+}
+
+// GetHotels returns a copy of all hotels
+func (s *HotelService) GetHotels() []models.Hotel {
+	snap := s.snapshot.Load()
+	hotels := make([]models.Hotel, 0, snap.count)
+	for _, hotel := range snap.hotels {
+		if hotel != nil {
+			hotels = append(hotels, *hotel)
+		}
+	}
+	return hotels
+}
+
+// Count returns the number of hotels
+func (s *HotelService) Count() int {
+	return s.snapshot.Load().count
+}
+
 // GetHotelByID returns a hotel by its ID
 func (s *HotelService) GetHotelByID(id string) (*models.Hotel, error) {
-	for _, hotel := range s.Hotels {
-		if hotel.ID == id {
-			return &hotel, nil
-		}
+	snap := s.snapshot.Load()
+	if i, ok := snap.byID.get(id); ok {
+		hotel := *snap.hotels[i]
+		return &hotel, nil
 	}
-	return nil, errors.New("hotel not found")
+	return nil, ErrHotelNotFound
 }
 
-// SearchHotels filters hotels based on search parameters
-func (s *HotelService) SearchHotels(params models.SearchParams) []models.Hotel {
-	var results []models.Hotel
+// CreateHotel validates and stores a new hotel.
+// ID, type, metadata path and timestamps are generated by the server.
+func (s *HotelService) CreateHotel(hotel models.Hotel) (*models.Hotel, error) {
+	now := time.Now().UnixMilli()
+	hotel.ID = uuid.New().String()
+	hotel.Type = "hotel"
+	hotel.Metadata.Path = "/hotels/" + hotel.ID
+	hotel.Created = now
+	hotel.Modified = now
+	hotel.Amenities = models.AmenityCodes(hotel.AmenityMask)
 
-	// Apply filters
-	for _, hotel := range s.Hotels {
-		// Skip if doesn't match name filter (case-insensitive, partial match)
-		if params.Name != "" && !strings.Contains(strings.ToLower(hotel.Name), strings.ToLower(params.Name)) {
-			continue
-		}
+	if err := validateHotel(hotel); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snap := s.snapshot.Load()
+	stored := hotel
+	s.lastSeq++
+	s.snapshot.Store(snap.update(len(snap.hotels), &stored, s.lastSeq))
+
+	return &hotel, nil
+}
+
+// ReplaceHotel replaces all the client-editable fields of an existing hotel
+func (s *HotelService) ReplaceHotel(id string, hotel models.Hotel) (*models.Hotel, error) {
+	return s.updateHotel(id, func(existing models.Hotel) (models.Hotel, error) {
+		return hotel, nil
+	})
+}
+
+// PatchHotel applies a JSON merge patch (RFC 7396) to an existing hotel
+func (s *HotelService) PatchHotel(id string, patch []byte) (*models.Hotel, error) {
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, newValidationError("body", "invalid merge patch: "+err.Error())
+	}
 
-		// Skip if doesn't match city filter (case-insensitive)
-		if params.City != "" && !strings.EqualFold(hotel.City, params.City) {
-			continue
+	return s.updateHotel(id, func(existing models.Hotel) (models.Hotel, error) {
+		// Apply the patch to the JSON representation of the hotel
+		data, err := json.Marshal(existing)
+		if err != nil {
+			return models.Hotel{}, fmt.Errorf("error encoding hotel: %w", err)
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return models.Hotel{}, fmt.Errorf("error decoding hotel: %w", err)
+		}
+		data, err = json.Marshal(mergePatch(doc, patchDoc))
+		if err != nil {
+			return models.Hotel{}, fmt.Errorf("error encoding hotel: %w", err)
 		}
 
-		// Skip if doesn't match country code filter
-		if params.CountryCode != "" && hotel.CountryCode != params.CountryCode {
-			continue
+		var hotel models.Hotel
+		if err := decodeStrict(data, &hotel); err != nil {
+			return models.Hotel{}, newValidationError("body", "invalid merge patch: "+err.Error())
 		}
+		return hotel, nil
+	})
+}
+
+// DeleteHotel deletes a hotel.
+// Callers should use ReservationService.DeleteHotel so the hotel's reservations are handled too.
+func (s *HotelService) DeleteHotel(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snap := s.snapshot.Load()
+	i, ok := snap.byID.get(id)
+	if !ok {
+		return ErrHotelNotFound
+	}
+
+	s.snapshot.Store(snap.update(i, nil, 0).compact())
+
+	return nil
+}
+
+// updateHotel replaces an existing hotel with the result of update, keeping the server generated fields
+func (s *HotelService) updateHotel(id string, update func(existing models.Hotel) (models.Hotel, error)) (*models.Hotel, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snap := s.snapshot.Load()
+	i, ok := snap.byID.get(id)
+	if !ok {
+		return nil, ErrHotelNotFound
+	}
+	existing := *snap.hotels[i]
 
-		// Skip if below minimum rate
-		if params.MinRate > 0 && hotel.LowRate < params.MinRate {
-			continue
+	hotel, err := update(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep server generated fields
+	hotel.ID = existing.ID
+	hotel.Type = "hotel"
+	hotel.Metadata.Path = "/hotels/" + existing.ID
+	hotel.Created = existing.Created
+	hotel.Modified = time.Now().UnixMilli()
+	hotel.Amenities = models.AmenityCodes(hotel.AmenityMask)
+
+	if err := validateHotel(hotel); err != nil {
+		return nil, err
+	}
+
+	stored := hotel
+	s.snapshot.Store(snap.update(i, &stored, snap.seqs[i]))
+
+	return &hotel, nil
+}
+
+// SearchPage is a page of hotel search results, with its position in the full result list
+type SearchPage struct {
+	Hotels []models.HotelResult
+	Total  int                  // number of hotels matching the search, only counted with params.CountTotal
+	Offset int                  // number of matching hotels before the page
+	Next   *models.SearchCursor // cursor of the next page, nil on the last page
+	Prev   *models.SearchCursor // cursor of the previous page, nil on the first page
+}
+
+// SearchHotels filters hotels based on search parameters.
+// Full-text searches (params.Query) score each hotel and sort the results by relevance;
+// geo searches (params.Near) compute each hotel's distance and, without a query, sort the results by it.
+// Availability searches (params.CheckIn/CheckOut) only return hotels free for the stay.
+// params.Sort overrides both orders. Pages are selected by params.Offset, or by params.Cursor
+// which stays consistent while hotels are inserted or deleted.
+//
+// Only the matches the page needs are kept. Searches in insertion order stop as soon as the page
+// is full, unless params.CountTotal asks for the number of all the matches.
+func (s *HotelService) SearchHotels(params models.SearchParams) SearchPage {
+	// Apply pagination
+	if params.Limit <= 0 {
+		params.Limit = 20 // Default limit
+	}
+
+	if params.Offset < 0 || params.Cursor != nil {
+		params.Offset = 0
+	}
+
+	order, ordered := searchOrder(params)
+	backward := params.Cursor != nil && params.Cursor.Backward
+	var edge searchMatch
+	if params.Cursor != nil {
+		edge = cursorMatch(*params.Cursor)
+	}
+
+	// Keep the matches of the page and the one after it, which tells whether there is a next page.
+	// Backward pages are the last matches before the cursor: the first ones in reverse order.
+	top := &topMatches{k: params.Offset + params.Limit + 1, before: order, inOrder: ordered && !backward}
+	if backward {
+		top.before = func(a, b searchMatch) bool { return order(b, a) }
+	}
+	total, before, after := 0, 0, 0 // matches, and those before and after the cursor
+	snap := s.snapshot.Load()
+	scores, corpus := snap.textScores(params)
+	snap.eachMatch(params, scores, s.availableFilter(params), func(i int, score, km float64) bool {
+		m := searchMatch{hotel: snap.hotels[i], seq: snap.seqs[i], name: snap.names[i], score: score, km: km}
+		total++
+
+		// Keep the matches on the requested side of the cursor
+		if params.Cursor != nil {
+			switch {
+			case order(m, edge):
+				before++
+				if !backward {
+					return true
+				}
+			case order(edge, m):
+				after++
+				if backward {
+					// Matches come in insertion order: once past the cursor, no match before it follows
+					return params.CountTotal || !ordered
+				}
+			default:
+				// The hotel at the cursor, which comes before a forward page
+				if !backward {
+					before++
+				}
+				return true
+			}
 		}
 
-		// Skip if above maximum rate
-		if params.MaxRate > 0 && hotel.HighRate > params.MaxRate {
-			continue
+		// Matches come in insertion order: once the page is full, the following ones cannot make it
+		top.push(m)
+		return params.CountTotal || !ordered || backward || len(top.matches) < top.k
+	})
+
+	page := SearchPage{}
+	if params.CountTotal {
+		page.Total = total
+	}
+
+	// Select the page in the search order
+	selected := top.sorted()
+	hasNext := false
+	if backward {
+		if len(selected) > params.Limit {
+			selected = selected[:params.Limit]
+		}
+		for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+			selected[i], selected[j] = selected[j], selected[i]
 		}
+		page.Offset = before - len(selected)
+		hasNext = after > 0
+	} else {
+		hasNext = len(selected) > params.Offset+params.Limit
+		if params.Offset >= len(selected) {
+			selected = nil
+		} else if end := params.Offset + params.Limit; end < len(selected) {
+			selected = selected[params.Offset:end]
+		} else {
+			selected = selected[params.Offset:]
+		}
+		page.Offset = before + params.Offset
+	}
 
-		// Skip if below minimum rating
-		if params.MinRating > 0 && hotel.HotelRating < params.MinRating {
-			continue
+	// Build the results of the requested page
+	page.Hotels = make([]models.HotelResult, len(selected))
+	for i, m := range selected {
+		page.Hotels[i].Hotel = *m.hotel
+		if params.Near != nil {
+			distance := convertDistance(m.km, params.DistanceUnit)
+			page.Hotels[i].Distance = &distance
+			page.Hotels[i].DistanceUnit = params.DistanceUnit
+		}
+		if params.Query != "" {
+			score := math.Round(m.score*1000) / 1000
+			page.Hotels[i].Score = &score
+		}
+		if checkIn, checkOut, ok := stayDates(params); ok {
+			available := true
+			page.Hotels[i].Available = &available
+			page.Hotels[i].Nights = models.CountNights(checkIn, checkOut)
 		}
+	}
 
-		// Skip if above maximum rating
-		if params.MaxRating > 0 && hotel.HotelRating > params.MaxRating {
-			continue
+	// Point to the adjacent pages
+	if len(selected) > 0 && page.Offset > 0 {
+		page.Prev = selected[0].cursor(params, corpus, true)
+	}
+	if len(selected) > 0 && hasNext {
+		page.Next = selected[len(selected)-1].cursor(params, corpus, false)
+	}
+	return page
+}
+
+// searchMatch is a hotel matching a search, with the values computed for it
+type searchMatch struct {
+	hotel *models.Hotel
+	seq   int64   // insertion sequence
+	name  string  // folded name, for sorting
+	km    float64 // distance to the near point, for geo searches
+	score float64 // relevance to the query, for full-text searches
+}
+
+// searchOrder returns the order of the results of a search, and whether matches collected
+// in insertion order are already in that order
+func searchOrder(params models.SearchParams) (func(a, b searchMatch) bool, bool) {
+	switch {
+	case len(params.Sort) > 0:
+		return sortOrder(params.Sort), false
+	case params.Query != "":
+		return searchMatch.moreRelevant, false
+	case params.Near != nil:
+		return searchMatch.closer, false
+	}
+	return searchMatch.inserted, true
+}
+
+// inserted orders matches by insertion, i.e. file order
+func (m searchMatch) inserted(other searchMatch) bool {
+	return m.seq < other.seq
+}
+
+// closer orders matches by distance, then by insertion
+func (m searchMatch) closer(other searchMatch) bool {
+	if m.km != other.km {
+		return m.km < other.km
+	}
+	return m.inserted(other)
+}
+
+// moreRelevant orders matches by decreasing relevance, then by distance and insertion
+func (m searchMatch) moreRelevant(other searchMatch) bool {
+	if m.score != other.score {
+		return m.score > other.score
+	}
+	return m.closer(other)
+}
+
+// cursor returns the cursor of the page after (or, if backward, before) this match.
+// Only the values used by the search order are kept, and for relevance orders the corpus statistics
+// the scores were computed with.
+func (m searchMatch) cursor(params models.SearchParams, corpus *models.TextCorpus, backward bool) *models.SearchCursor {
+	c := &models.SearchCursor{Backward: backward, Seq: m.seq, ID: m.hotel.ID}
+	if len(params.Sort) == 0 {
+		c.Score = m.score
+		c.Distance = m.km
+		c.Corpus = corpus
+		return c
+	}
+	for _, key := range params.Sort {
+		switch key.Field {
+		case models.SortLowRate:
+			c.LowRate = m.hotel.LowRate
+		case models.SortHighRate:
+			c.HighRate = m.hotel.HighRate
+		case models.SortHotelRating:
+			c.HotelRating = m.hotel.HotelRating
+		case models.SortTripAdvisorRating:
+			c.TripAdvisorRating = m.hotel.TripAdvisorRating
+		case models.SortName:
+			c.Name = m.hotel.Name
+		case models.SortModified:
+			c.Modified = m.hotel.Modified
+		case models.SortDistance:
+			c.Distance = m.km
 		}
+	}
+	return c
+}
 
-		// Skip if doesn't match amenity mask (bitwise AND)
-		if params.AmenityMask > 0 && (hotel.AmenityMask&params.AmenityMask) != params.AmenityMask {
-			continue
+// cursorMatch rebuilds the match at the edge of a page from its cursor, to compare matches with it
+func cursorMatch(c models.SearchCursor) searchMatch {
+	return searchMatch{
+		hotel: &models.Hotel{
+			ID:                c.ID,
+			Name:              c.Name,
+			LowRate:           c.LowRate,
+			HighRate:          c.HighRate,
+			HotelRating:       c.HotelRating,
+			TripAdvisorRating: c.TripAdvisorRating,
+			Modified:          c.Modified,
+		},
+		seq:   c.Seq,
+		name:  foldText(c.Name),
+		km:    c.Distance,
+		score: c.Score,
+	}
+}
+
+// topMatches keeps the first k of the matches pushed to it, in the before order.
+// Once full, it is a heap whose root is the last match kept, so a push is O(log k) whatever the number of matches.
+type topMatches struct {
+	matches []searchMatch
+	k       int
+	before  func(a, b searchMatch) bool
+	inOrder bool // matches are pushed in the before order, so the first k pushed are kept as they are
+}
+
+// push offers a match, keeping it if it is among the first k so far
+func (t *topMatches) push(m searchMatch) {
+	if len(t.matches) < t.k {
+		t.matches = append(t.matches, m)
+		if len(t.matches) == t.k && !t.inOrder {
+			heap.Init(t)
 		}
+		return
+	}
+	if !t.inOrder && t.before(m, t.matches[0]) {
+		t.matches[0] = m
+		heap.Fix(t, 0)
+	}
+}
 
-		// Add hotel to results if it passed all filters
-		results = append(results, hotel)
+// sorted returns the matches kept, in the before order
+func (t *topMatches) sorted() []searchMatch {
+	if t.inOrder {
+		return t.matches
 	}
+	sort.Slice(t.matches, func(a, b int) bool {
+		return t.before(t.matches[a], t.matches[b])
+	})
+	return t.matches
+}
 
-	// Apply pagination
-	if params.Limit <= 0 {
-		params.Limit = 20 // Default limit
+func (t *topMatches) Len() int           { return len(t.matches) }
+func (t *topMatches) Less(i, j int) bool { return t.before(t.matches[j], t.matches[i]) }
+func (t *topMatches) Swap(i, j int)      { t.matches[i], t.matches[j] = t.matches[j], t.matches[i] }
+func (t *topMatches) Push(x interface{}) { t.matches = append(t.matches, x.(searchMatch)) }
+func (t *topMatches) Pop() interface{} {
+	m := t.matches[len(t.matches)-1]
+	t.matches = t.matches[:len(t.matches)-1]
+	return m
+}
+
+// matchesSearchParams reports whether a hotel passes all the search filters
+func matchesSearchParams(hotel *models.Hotel, params models.SearchParams) bool {
+	// Skip if doesn't match name filter (case-insensitive, partial match)
+	if params.Name != "" && !strings.Contains(strings.ToLower(hotel.Name), strings.ToLower(params.Name)) {
+		return false
 	}
 
-	if params.Offset < 0 {
-		params.Offset = 0
+	// Skip if doesn't match city filter (case-insensitive)
+	if params.City != "" && !strings.EqualFold(hotel.City, params.City) {
+		return false
+	}
+
+	// Skip if doesn't match country code filter
+	if params.CountryCode != "" && hotel.CountryCode != params.CountryCode {
+		return false
+	}
+
+	// Skip if below minimum rate
+	if params.MinRate > 0 && hotel.LowRate < params.MinRate {
+		return false
+	}
+
+	// Skip if above maximum rate
+	if params.MaxRate > 0 && hotel.HighRate > params.MaxRate {
+		return false
+	}
+
+	// Skip if below minimum rating
+	if params.MinRating > 0 && hotel.HotelRating < params.MinRating {
+		return false
+	}
+
+	// Skip if above maximum rating
+	if params.MaxRating > 0 && hotel.HotelRating > params.MaxRating {
+		return false
+	}
+
+	// Skip if outside the bounding box
+	if params.BBox != nil && !params.BBox.Contains(hotel.Location) {
+		return false
 	}
 
-	// Handle pagination boundaries
-	end := params.Offset + params.Limit
-	if end > len(results) {
-		end = len(results)
+	// Skip if doesn't match amenity mask (bitwise AND)
+	if params.AmenityMask > 0 && (hotel.AmenityMask&params.AmenityMask) != params.AmenityMask {
+		return false
 	}
 
-	// Return paginated results
-	if params.Offset < len(results) {
-		return results[params.Offset:end]
+	// Skip if has none of the wanted amenities
+	if params.AnyAmenityMask > 0 && hotel.AmenityMask&params.AnyAmenityMask == 0 {
+		return false
 	}
 
-	// Return empty slice if offset is out of bounds
-	return []models.Hotel{}
-}
\ No newline at end of file
+	return true
+}
+
+// validateHotel checks the client-editable fields of a hotel
+func validateHotel(hotel models.Hotel) error {
+	problems := &ValidationError{}
+
+	if strings.TrimSpace(hotel.Name) == "" {
+		problems.Add("name", "is required")
+	}
+	if strings.TrimSpace(hotel.City) == "" {
+		problems.Add("city", "is required")
+	}
+	if !models.CountryCodePattern.MatchString(hotel.CountryCode) {
+		problems.Add("countryCode", "must be a 2-letter uppercase ISO code")
+	}
+	if hotel.HotelRating < 0 || hotel.HotelRating > 5 {
+		problems.Add("hotelRating", "must be between 0 and 5")
+	}
+	if hotel.TripAdvisorRating < 0 || hotel.TripAdvisorRating > 5 {
+		problems.Add("tripAdvisorRating", "must be between 0 and 5")
+	}
+	if hotel.ConfidenceRating < 0 || hotel.ConfidenceRating > 100 {
+		problems.Add("confidenceRating", "must be between 0 and 100")
+	}
+	if hotel.LowRate < 0 {
+		problems.Add("lowRate", "must not be negative")
+	}
+	if hotel.HighRate < 0 {
+		problems.Add("highRate", "must not be negative")
+	}
+	if hotel.LowRate > hotel.HighRate {
+		problems.Add("lowRate", "must be less than or equal to highRate")
+	}
+	if hotel.Location.Latitude < -90 || hotel.Location.Latitude > 90 {
+		problems.Add("location.latitude", "must be between -90 and 90")
+	}
+	if hotel.Location.Longitude < -180 || hotel.Location.Longitude > 180 {
+		problems.Add("location.longitude", "must be between -180 and 180")
+	}
+
+	return problems.OrNil()
+}
+
+// mergePatch applies a JSON merge patch (RFC 7396) to a decoded JSON document
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// decodeStrict decodes JSON into v, rejecting unknown fields
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// benchmarkHotels is the number of hotels of the benchmark data, the size the indexes must stay fast at
+const benchmarkHotels = 100000
+
+var (
+	benchmarkCities = []string{
+		"Seattle", "Paris", "London", "Berlin", "Madrid", "Rome", "Lisbon", "Vienna", "Prague", "Zürich",
+		"New York", "Chicago", "Boston", "Denver", "Austin", "Tokyo", "Osaka", "Sydney", "Perth", "Lima",
+	}
+	benchmarkWords = []string{
+		"grand", "plaza", "royal", "garden", "harbor", "central", "park", "palace", "boutique", "inn",
+		"suites", "lodge", "resort", "tower", "river", "bay", "station", "museum", "old", "town",
+	}
+)
+
+// testHotels generates n hotels with deterministic, varied names, cities, rates and locations
+func testHotels(n int) []models.Hotel {
+	rng := rand.New(rand.NewSource(1))
+	hotels := make([]models.Hotel, n)
+	for i := range hotels {
+		city := benchmarkCities[rng.Intn(len(benchmarkCities))]
+		name := fmt.Sprintf("%s %s %s %d", benchmarkWords[rng.Intn(len(benchmarkWords))],
+			benchmarkWords[rng.Intn(len(benchmarkWords))], city, i)
+		lowRate := float64(50 + rng.Intn(500))
+		hotels[i] = models.Hotel{
+			ID:                  fmt.Sprintf("00000000-0000-4000-8000-%012d", i),
+			Type:                "hotel",
+			Name:                name,
+			City:                city,
+			CountryCode:         "US",
+			HotelRating:         float64(rng.Intn(11)) / 2,
+			TripAdvisorRating:   float64(rng.Intn(11)) / 2,
+			LowRate:             lowRate,
+			HighRate:            lowRate + float64(rng.Intn(300)),
+			LocationDescription: "Near " + benchmarkWords[rng.Intn(len(benchmarkWords))] + " " + city,
+			ShortDescription:    "A " + benchmarkWords[rng.Intn(len(benchmarkWords))] + " hotel in " + city,
+			Location: models.Location{
+				Latitude:  rng.Float64()*120 - 60,
+				Longitude: rng.Float64()*360 - 180,
+			},
+		}
+	}
+	return hotels
+}
+
+// TestHotelSnapshotUpdates checks that the indexes updated by single-hotel writes find the same hotels
+// as indexes built from scratch, after interleaved creates, replaces and deletes (enough deletes to compact)
+func TestHotelSnapshotUpdates(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	hotels := testHotels(600)
+	s := NewHotelService()
+	s.SetHotels(hotels[:200])
+
+	var ids []string
+	for _, hotel := range s.GetHotels() {
+		ids = append(ids, hotel.ID)
+	}
+	for n, hotel := range hotels[200:] {
+		switch op := rng.Intn(4); {
+		case op == 0 || len(ids) == 0:
+			created, err := s.CreateHotel(hotel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, created.ID)
+		case op == 1:
+			if _, err := s.ReplaceHotel(ids[rng.Intn(len(ids))], hotel); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			k := rng.Intn(len(ids))
+			if err := s.DeleteHotel(ids[k]); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids[:k], ids[k+1:]...)
+		}
+
+		if n%50 == 0 || n == len(hotels)-201 {
+			checkSameSearches(t, s)
+		}
+	}
+	if got := s.Count(); got != len(ids) {
+		t.Fatalf("Count() = %d, want %d", got, len(ids))
+	}
+	for _, id := range ids {
+		if _, err := s.GetHotelByID(id); err != nil {
+			t.Fatalf("GetHotelByID(%s): %v", id, err)
+		}
+	}
+}
+
+// checkSameSearches compares searches of a service with the same searches of a service rebuilt from its hotels
+func checkSameSearches(t *testing.T, s *HotelService) {
+	t.Helper()
+	rebuilt := NewHotelService()
+	rebuilt.SetHotels(s.GetHotels())
+
+	searches := []models.SearchParams{
+		{},
+		{City: "Paris"},
+		{City: "zürich"},
+		{CountryCode: "US"},
+		{MinRate: 300},
+		{MinRating: 2, MaxRating: 3.5},
+		{Sort: []models.SortKey{{Field: models.SortLowRate}, {Field: models.SortName, Descending: true}}},
+		{Query: "royal harbor"},
+		{Near: &models.Location{Latitude: 40, Longitude: 10}, Radius: 3000},
+		{BBox: &models.BoundingBox{MinLatitude: -20, MinLongitude: 170, MaxLatitude: 20, MaxLongitude: -170}},
+	}
+	for _, params := range searches {
+		params.Limit, params.CountTotal = 1000, true
+		got, want := s.SearchHotels(params), rebuilt.SearchHotels(params)
+		if got.Total != want.Total || len(got.Hotels) != len(want.Hotels) {
+			t.Fatalf("search %+v: %d hotels, want %d", params, got.Total, want.Total)
+		}
+		for i := range got.Hotels {
+			if got.Hotels[i].ID != want.Hotels[i].ID {
+				t.Fatalf("search %+v: hotel %d is %s, want %s", params, i, got.Hotels[i].ID, want.Hotels[i].ID)
+			}
+		}
+	}
+	for _, query := range []string{"roy", "grand pla", "paris"} {
+		got, want := s.Suggest(query, 10), rebuilt.Suggest(query, 10)
+		if len(got) != len(want) {
+			t.Fatalf("Suggest(%q): %d suggestions, want %d", query, len(got), len(want))
+		}
+		for i := range got {
+			if got[i].Text != want[i].Text {
+				t.Fatalf("Suggest(%q): suggestion %d is %q, want %q", query, i, got[i].Text, want[i].Text)
+			}
+		}
+	}
+}
+
+// TestSearchPages walks the pages of searches by offset and by cursor, in both directions, checking
+// they hold the full result list in order with the right offsets and neighbours, whether or not the total is counted
+func TestSearchPages(t *testing.T) {
+	s := NewHotelService()
+	s.SetHotels(testHotels(300))
+	searches := map[string]models.SearchParams{
+		"insertion order": {},
+		"filtered":        {MinRating: 2, MaxRating: 4},
+		"sorted":          {Sort: []models.SortKey{{Field: models.SortHotelRating, Descending: true}}},
+		"relevance":       {Query: "royal harbor"},
+		"distance":        {Near: &models.Location{Latitude: 40, Longitude: 10}},
+		"no match":        {City: "Atlantis"},
+	}
+	for name, search := range searches {
+		t.Run(name, func(t *testing.T) {
+			all := search
+			all.Limit, all.CountTotal = 1000, true
+			want := s.SearchHotels(all)
+			if want.Total != len(want.Hotels) || want.Next != nil || want.Prev != nil {
+				t.Fatalf("full search: total %d, %d hotels, next %v, prev %v", want.Total, len(want.Hotels), want.Next, want.Prev)
+			}
+
+			for _, countTotal := range []bool{false, true} {
+				params := search
+				params.Limit, params.CountTotal = 7, countTotal
+				check := func(how string, page SearchPage, offset int) {
+					t.Helper()
+					end := offset + len(page.Hotels)
+					if page.Offset != offset || end > len(want.Hotels) || len(page.Hotels) != params.Limit && end != len(want.Hotels) {
+						t.Fatalf("%s: page of %d hotels at offset %d, want %d", how, len(page.Hotels), page.Offset, offset)
+					}
+					for i, hotel := range page.Hotels {
+						if hotel.ID != want.Hotels[offset+i].ID {
+							t.Fatalf("%s: hotel %d is %s, want %s", how, offset+i, hotel.ID, want.Hotels[offset+i].ID)
+						}
+					}
+					if wantTotal := map[bool]int{true: len(want.Hotels)}[countTotal]; page.Total != wantTotal {
+						t.Fatalf("%s: total %d, want %d", how, page.Total, wantTotal)
+					}
+					if (page.Next != nil) != (len(page.Hotels) > 0 && end < len(want.Hotels)) {
+						t.Fatalf("%s at offset %d: next cursor %v", how, offset, page.Next)
+					}
+					if (page.Prev != nil) != (len(page.Hotels) > 0 && offset > 0) {
+						t.Fatalf("%s at offset %d: prev cursor %v", how, offset, page.Prev)
+					}
+				}
+
+				// Forward by offset, then by cursor, then back by cursor from the last page
+				for offset := 0; offset < len(want.Hotels)+params.Limit; offset += params.Limit {
+					params.Offset = offset
+					page := s.SearchHotels(params)
+					if offset >= len(want.Hotels) {
+						if len(page.Hotels) != 0 || page.Offset != offset {
+							t.Fatalf("offset %d past the end: %d hotels at offset %d", offset, len(page.Hotels), page.Offset)
+						}
+						continue
+					}
+					check("offset", page, offset)
+				}
+				params.Offset = 0
+				page := s.SearchHotels(params)
+				for offset := 0; page.Next != nil; {
+					offset += len(page.Hotels)
+					params.Cursor = page.Next
+					page = s.SearchHotels(params)
+					check("next cursor", page, offset)
+				}
+				for offset := page.Offset; page.Prev != nil; {
+					offset -= params.Limit
+					params.Cursor = page.Prev
+					page = s.SearchHotels(params)
+					check("prev cursor", page, offset)
+				}
+			}
+		})
+	}
+}
+
+// TestRelevanceCursor checks that the next page of a full-text search stays the same while hotels are
+// inserted or deleted, although that changes the corpus statistics the relevance is computed from
+func TestRelevanceCursor(t *testing.T) {
+	s := NewHotelService()
+	s.SetHotels(testHotels(400))
+	params := models.SearchParams{Query: "royal", Limit: 10}
+	first := s.SearchHotels(params)
+	params.Cursor = first.Next
+	want := s.SearchHotels(params)
+	if first.Next == nil || len(want.Hotels) != 10 {
+		t.Fatalf("no full second page: %+v", want)
+	}
+
+	// Add longer hotels without the query term, and delete hotels of the first page
+	for _, hotel := range testHotels(200) {
+		hotel.Name = "Grand Plaza Hotel Far From Everything " + hotel.City
+		hotel.LocationDescription = "Near the " + hotel.City + " airport and the old harbor station"
+		hotel.ShortDescription = "A long description of a hotel that has nothing to do with the query"
+		if _, err := s.CreateHotel(hotel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, result := range first.Hotels[:5] {
+		if err := s.DeleteHotel(result.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := s.SearchHotels(params)
+	if len(got.Hotels) != len(want.Hotels) {
+		t.Fatalf("%d hotels on the second page, want %d", len(got.Hotels), len(want.Hotels))
+	}
+	for i := range got.Hotels {
+		if got.Hotels[i].ID != want.Hotels[i].ID || *got.Hotels[i].Score != *want.Hotels[i].Score {
+			t.Errorf("hotel %d is %s (score %v), want %s (score %v)", i,
+				got.Hotels[i].ID, *got.Hotels[i].Score, want.Hotels[i].ID, *want.Hotels[i].Score)
+		}
+	}
+}
+
+// benchmarkService returns a hotel service holding benchmarkHotels hotels
+func benchmarkService(b *testing.B) *HotelService {
+	b.Helper()
+	s := NewHotelService()
+	s.SetHotels(testHotels(benchmarkHotels))
+	b.ResetTimer()
+	return s
+}
+
+func BenchmarkGetHotelByID(b *testing.B) {
+	s := benchmarkService(b)
+	for i := 0; i < b.N; i++ {
+		id := fmt.Sprintf("00000000-0000-4000-8000-%012d", i%benchmarkHotels)
+		if _, err := s.GetHotelByID(id); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSearchHotels(b *testing.B) {
+	searches := map[string]models.SearchParams{
+		"city":     {City: "Paris", Limit: 20},
+		"rate":     {MinRate: 500, Limit: 20},
+		"rating":   {MinRating: 4.5, Limit: 20},
+		"sort":     {City: "Lisbon", Sort: []models.SortKey{{Field: models.SortLowRate}}, Limit: 20},
+		"text":     {Query: "royal harbor", Limit: 20},
+		"near":     {Near: &models.Location{Latitude: 47.6, Longitude: -122.3}, Radius: 200, Limit: 20},
+		"bbox":     {BBox: &models.BoundingBox{MinLatitude: 40, MinLongitude: -10, MaxLatitude: 50, MaxLongitude: 10}, Limit: 20},
+		"unfilter": {Limit: 20},
+		"offset":   {Limit: 20, Offset: 50000},
+		"total":    {Limit: 20, CountTotal: true},
+	}
+	s := benchmarkService(b)
+	for name, params := range searches {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s.SearchHotels(params)
+			}
+		})
+	}
+}
+
+func BenchmarkCreateHotel(b *testing.B) {
+	s := benchmarkService(b)
+	hotel := testHotels(1)[0]
+	for i := 0; i < b.N; i++ {
+		if _, err := s.CreateHotel(hotel); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
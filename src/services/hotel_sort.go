@@ -0,0 +1,59 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// sortOrder returns the order of matches for the given sort keys.
+// Ties are broken by hotel ID, so pages stay stable whatever the storage order.
+func sortOrder(keys []models.SortKey) func(a, b searchMatch) bool {
+	return func(a, b searchMatch) bool {
+		for _, key := range keys {
+			if c := compareMatches(key.Field, a, b); c != 0 {
+				if key.Descending {
+					return c > 0
+				}
+				return c < 0
+			}
+		}
+		return a.hotel.ID < b.hotel.ID
+	}
+}
+
+// compareMatches compares two matches by a sort field, returning -1, 0 or +1
+func compareMatches(field string, a, b searchMatch) int {
+	x, y := a.hotel, b.hotel
+	switch field {
+	case models.SortLowRate:
+		return compareFloats(x.LowRate, y.LowRate)
+	case models.SortHighRate:
+		return compareFloats(x.HighRate, y.HighRate)
+	case models.SortHotelRating:
+		return compareFloats(x.HotelRating, y.HotelRating)
+	case models.SortTripAdvisorRating:
+		return compareFloats(x.TripAdvisorRating, y.TripAdvisorRating)
+	case models.SortName:
+		// Case and accent insensitive, then exact
+		if c := strings.Compare(a.name, b.name); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Name, y.Name)
+	case models.SortModified:
+		return compareFloats(float64(x.Modified), float64(y.Modified))
+	case models.SortDistance:
+		return compareFloats(a.km, b.km)
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
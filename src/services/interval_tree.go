@@ -0,0 +1,215 @@
+package services
+
+import (
+	"time"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// reservationRecord is a stored reservation together with its parsed dates
+type reservationRecord struct {
+	reservation models.Reservation
+	start       time.Time
+	end         time.Time
+}
+
+// intervalTree indexes reservation records by their date range.
+//
+// It is an AVL tree ordered by (start, reservation ID) where every node also
+// keeps the latest end date of its subtree, so overlap queries can skip whole
+// subtrees: inserts, deletes and overlap checks are O(log n), and listing the
+// k reservations overlapping a range is O(log n + k).
+type intervalTree struct {
+	root *intervalNode
+}
+
+// intervalNode is a node of an intervalTree
+type intervalNode struct {
+	record *reservationRecord
+	maxEnd time.Time // latest end date in this subtree
+	height int
+	left   *intervalNode
+	right  *intervalNode
+}
+
+// Insert adds a record to the tree
+func (t *intervalTree) Insert(record *reservationRecord) {
+	t.root = insertNode(t.root, record)
+}
+
+// Delete removes a record from the tree, reporting whether it was found.
+// The record's start date and ID must not have changed since it was inserted.
+func (t *intervalTree) Delete(record *reservationRecord) bool {
+	var deleted bool
+	t.root, deleted = deleteNode(t.root, record)
+	return deleted
+}
+
+// Overlapping calls fn, in start date order, for every record sharing a night with
+// the given range (see models.IsOverlapping). Iteration stops when fn returns false.
+func (t *intervalTree) Overlapping(start, end time.Time, fn func(record *reservationRecord) bool) {
+	visitConflicting(t.root, start, end, true, fn)
+}
+
+// Conflicting calls fn, in start date order, for every record conflicting with a stay over the
+// given range (see models.IsConflicting). Iteration stops when fn returns false.
+func (t *intervalTree) Conflicting(start, end time.Time, sameDayTurnover bool, fn func(record *reservationRecord) bool) {
+	visitConflicting(t.root, start, end, sameDayTurnover, fn)
+}
+
+// Ascend calls fn for every record in start date order. Iteration stops when fn returns false.
+func (t *intervalTree) Ascend(fn func(record *reservationRecord) bool) {
+	ascendNode(t.root, fn)
+}
+
+// compareRecords orders records by start date, then by reservation ID
+func compareRecords(a, b *reservationRecord) int {
+	switch {
+	case a.start.Before(b.start):
+		return -1
+	case a.start.After(b.start):
+		return 1
+	case a.reservation.ID < b.reservation.ID:
+		return -1
+	case a.reservation.ID > b.reservation.ID:
+		return 1
+	}
+	return 0
+}
+
+func insertNode(node *intervalNode, record *reservationRecord) *intervalNode {
+	if node == nil {
+		return &intervalNode{record: record, maxEnd: record.end, height: 1}
+	}
+	if compareRecords(record, node.record) < 0 {
+		node.left = insertNode(node.left, record)
+	} else {
+		node.right = insertNode(node.right, record)
+	}
+	return rebalance(node)
+}
+
+func deleteNode(node *intervalNode, record *reservationRecord) (*intervalNode, bool) {
+	if node == nil {
+		return nil, false
+	}
+
+	var deleted bool
+	switch cmp := compareRecords(record, node.record); {
+	case cmp < 0:
+		node.left, deleted = deleteNode(node.left, record)
+	case cmp > 0:
+		node.right, deleted = deleteNode(node.right, record)
+	default:
+		deleted = true
+		if node.left == nil {
+			return node.right, true
+		}
+		if node.right == nil {
+			return node.left, true
+		}
+		// Replace with the in-order successor
+		successor := node.right
+		for successor.left != nil {
+			successor = successor.left
+		}
+		node.record = successor.record
+		node.right, _ = deleteNode(node.right, successor.record)
+	}
+
+	if !deleted {
+		return node, false
+	}
+	return rebalance(node), true
+}
+
+func visitConflicting(node *intervalNode, start, end time.Time, sameDayTurnover bool, fn func(record *reservationRecord) bool) bool {
+	// Nothing in this subtree ends late enough to conflict
+	if node == nil || node.maxEnd.Before(start) || (sameDayTurnover && node.maxEnd.Equal(start)) {
+		return true
+	}
+
+	if !visitConflicting(node.left, start, end, sameDayTurnover, fn) {
+		return false
+	}
+
+	// This node and its right subtree start too late to conflict
+	if node.record.start.After(end) || (sameDayTurnover && node.record.start.Equal(end)) {
+		return true
+	}
+
+	if models.IsConflicting(start, end, node.record.start, node.record.end, sameDayTurnover) && !fn(node.record) {
+		return false
+	}
+
+	return visitConflicting(node.right, start, end, sameDayTurnover, fn)
+}
+
+func ascendNode(node *intervalNode, fn func(record *reservationRecord) bool) bool {
+	if node == nil {
+		return true
+	}
+	return ascendNode(node.left, fn) && fn(node.record) && ascendNode(node.right, fn)
+}
+
+func nodeHeight(node *intervalNode) int {
+	if node == nil {
+		return 0
+	}
+	return node.height
+}
+
+// update recomputes the height and max end date of a node from its children
+func (node *intervalNode) update() {
+	node.height = 1 + maxInt(nodeHeight(node.left), nodeHeight(node.right))
+	node.maxEnd = node.record.end
+	if node.left != nil && node.left.maxEnd.After(node.maxEnd) {
+		node.maxEnd = node.left.maxEnd
+	}
+	if node.right != nil && node.right.maxEnd.After(node.maxEnd) {
+		node.maxEnd = node.right.maxEnd
+	}
+}
+
+func rotateLeft(node *intervalNode) *intervalNode {
+	pivot := node.right
+	node.right = pivot.left
+	pivot.left = node
+	node.update()
+	pivot.update()
+	return pivot
+}
+
+func rotateRight(node *intervalNode) *intervalNode {
+	pivot := node.left
+	node.left = pivot.right
+	pivot.right = node
+	node.update()
+	pivot.update()
+	return pivot
+}
+
+func rebalance(node *intervalNode) *intervalNode {
+	node.update()
+	balance := nodeHeight(node.left) - nodeHeight(node.right)
+	if balance > 1 {
+		if nodeHeight(node.left.left) < nodeHeight(node.left.right) {
+			node.left = rotateLeft(node.left)
+		}
+		return rotateRight(node)
+	}
+	if balance < -1 {
+		if nodeHeight(node.right.right) < nodeHeight(node.right.left) {
+			node.right = rotateRight(node.right)
+		}
+		return rotateLeft(node)
+	}
+	return node
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
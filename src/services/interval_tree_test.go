@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// TestIntervalTree compares the tree's queries with a scan of all records, after interleaved inserts and deletes
+func TestIntervalTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	randomStay := func() (time.Time, time.Time) {
+		start := first.AddDate(0, 0, rng.Intn(60))
+		return start, start.AddDate(0, 0, 1+rng.Intn(7))
+	}
+
+	var tree intervalTree
+	var records []*reservationRecord
+	for n := 0; n < 3000; n++ {
+		if len(records) == 0 || rng.Intn(3) > 0 {
+			start, end := randomStay()
+			record := &reservationRecord{
+				reservation: models.Reservation{ID: fmt.Sprintf("r%04d", n)},
+				start:       start,
+				end:         end,
+			}
+			tree.Insert(record)
+			records = append(records, record)
+		} else {
+			k := rng.Intn(len(records))
+			if !tree.Delete(records[k]) {
+				t.Fatalf("Delete(%s) = false, want true", records[k].reservation.ID)
+			}
+			if tree.Delete(records[k]) {
+				t.Fatalf("second Delete(%s) = true, want false", records[k].reservation.ID)
+			}
+			records = append(records[:k], records[k+1:]...)
+		}
+
+		sort.Slice(records, func(i, j int) bool { return compareRecords(records[i], records[j]) < 0 })
+		checkRecords(t, "Ascend", collect(tree.Ascend), records)
+
+		start, end := randomStay()
+		var overlapping, conflicting, turnover []*reservationRecord
+		for _, record := range records {
+			if models.IsOverlapping(start, end, record.start, record.end) {
+				overlapping = append(overlapping, record)
+			}
+			if models.IsConflicting(start, end, record.start, record.end, false) {
+				conflicting = append(conflicting, record)
+			}
+			if models.IsConflicting(start, end, record.start, record.end, true) {
+				turnover = append(turnover, record)
+			}
+		}
+		checkRecords(t, "Overlapping", collect(func(fn func(*reservationRecord) bool) {
+			tree.Overlapping(start, end, fn)
+		}), overlapping)
+		checkRecords(t, "Conflicting without turnover", collect(func(fn func(*reservationRecord) bool) {
+			tree.Conflicting(start, end, false, fn)
+		}), conflicting)
+		checkRecords(t, "Conflicting with turnover", collect(func(fn func(*reservationRecord) bool) {
+			tree.Conflicting(start, end, true, fn)
+		}), turnover)
+	}
+}
+
+// collect returns the records an iteration visits
+func collect(iterate func(fn func(record *reservationRecord) bool)) []*reservationRecord {
+	var records []*reservationRecord
+	iterate(func(record *reservationRecord) bool {
+		records = append(records, record)
+		return true
+	})
+	return records
+}
+
+// checkRecords fails the test unless got holds the wanted records, in order
+func checkRecords(t *testing.T, query string, got, want []*reservationRecord) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d records, want %d", query, len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("%s: record %d is %s, want %s", query, i, got[i].reservation.ID, want[i].reservation.ID)
+		}
+	}
+}
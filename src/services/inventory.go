@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// SetRoomTypes configures the room types of a hotel, replacing the ones generated from its property category
+func (s *ReservationService) SetRoomTypes(hotelID string, roomTypes []models.RoomType) error {
+	// Check if the hotel exists
+	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
+		return ErrHotelNotFound
+	}
+
+	// Validate room types
+	problems := &ValidationError{}
+	if len(roomTypes) == 0 {
+		problems.Add("roomTypes", "must not be empty")
+	}
+	seen := map[string]bool{}
+	for i, roomType := range roomTypes {
+		field := fmt.Sprintf("roomTypes[%d]", i)
+		switch {
+		case roomType.Code == "":
+			problems.Add(field+".code", "is required")
+		case seen[roomType.Code]:
+			problems.Add(field+".code", fmt.Sprintf("%q is used more than once", roomType.Code))
+		}
+		seen[roomType.Code] = true
+		if roomType.Count < 1 {
+			problems.Add(field+".count", "must be at least 1")
+		}
+	}
+	if err := problems.OrNil(); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.roomTypes[hotelID] = append([]models.RoomType(nil), roomTypes...)
+	return nil
+}
+
+// RoomTypes returns the room types of a hotel: the configured ones, else the ones generated
+// from its property category (see models.DefaultRoomTypes)
+func (s *ReservationService) RoomTypes(hotelID string) ([]models.RoomType, error) {
+	hotel, err := s.hotelService.GetHotelByID(hotelID)
+	if err != nil {
+		return nil, ErrHotelNotFound
+	}
+
+	s.mutex.RLock()
+	configured, ok := s.roomTypes[hotelID]
+	s.mutex.RUnlock()
+
+	if ok {
+		return append([]models.RoomType(nil), configured...), nil
+	}
+	return models.DefaultRoomTypes(*hotel), nil
+}
+
+// SetSameDayTurnover configures whether a room of a hotel can be booked again on the check-out day of its
+// previous stay. Hotels allow it unless configured otherwise.
+func (s *ReservationService) SetSameDayTurnover(hotelID string, allowed bool) error {
+	// Check if the hotel exists
+	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
+		return ErrHotelNotFound
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.turnover[hotelID] = allowed
+	return nil
+}
+
+// SameDayTurnover reports whether a hotel allows a stay to start on the day another one ends
+func (s *ReservationService) SameDayTurnover(hotelID string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	allowed, ok := s.turnover[hotelID]
+	return allowed || !ok
+}
+
+// GetRoomTypes returns the room types of a hotel. When a stay is given (checkIn and checkOut, YYYY-MM-DD),
+// each room type also has the number of its rooms left for the whole stay.
+func (s *ReservationService) GetRoomTypes(hotelID, checkIn, checkOut string) ([]models.RoomTypeAvailability, error) {
+	roomTypes, err := s.RoomTypes(hotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.RoomTypeAvailability, len(roomTypes))
+	for i, roomType := range roomTypes {
+		result[i].RoomType = roomType
+	}
+	if checkIn == "" && checkOut == "" {
+		return result, nil
+	}
+
+	// Validate dates
+	problems := &ValidationError{}
+	start, err := models.ParseDate(checkIn)
+	if err != nil {
+		problems.Add("checkIn", "must be a date in YYYY-MM-DD format")
+	}
+	end, err := models.ParseDate(checkOut)
+	if err != nil {
+		problems.Add("checkOut", "must be a date in YYYY-MM-DD format")
+	}
+	if len(problems.Fields) == 0 && !end.After(start) {
+		problems.Add("checkOut", "must be after checkIn")
+	}
+	if err := problems.OrNil(); err != nil {
+		return nil, err
+	}
+
+	// Count the rooms left by the reservations of the stay
+	turnover := s.SameDayTurnover(hotelID)
+	records := s.conflicting(hotelID, start, end, turnover)
+	for i := range result {
+		remaining := roomsLeft(result[i].RoomType, records, start, end, turnover, "")
+		result[i].Remaining = &remaining
+	}
+	return result, nil
+}
+
+// resolveRoomType picks the room type and checks the quantity a reservation asks for.
+// An empty code books the first room type, and a zero quantity books one room.
+func resolveRoomType(roomTypes []models.RoomType, code string, quantity int) (models.RoomType, int, error) {
+	problems := &ValidationError{}
+
+	roomType := roomTypes[0]
+	if code != "" {
+		var ok bool
+		if roomType, ok = models.FindRoomType(roomTypes, code); !ok {
+			codes := make([]string, len(roomTypes))
+			for i, roomType := range roomTypes {
+				codes[i] = roomType.Code
+			}
+			problems.Add("roomType", fmt.Sprintf("unknown room type %q, must be one of: %s", code, strings.Join(codes, ", ")))
+			return models.RoomType{}, 0, problems
+		}
+	}
+
+	if quantity == 0 {
+		quantity = 1
+	}
+	switch {
+	case quantity < 0:
+		problems.Add("quantity", "must be at least 1")
+	case quantity > roomType.Count:
+		problems.Add("quantity", fmt.Sprintf("must be at most %d, the number of %s rooms", roomType.Count, roomType.Code))
+	}
+	if err := problems.OrNil(); err != nil {
+		return models.RoomType{}, 0, err
+	}
+	return roomType, quantity, nil
+}
+
+// conflicting returns the reservations of a hotel holding rooms that conflict with a stay over the given dates,
+// in start date order
+func (s *ReservationService) conflicting(hotelID string, start, end time.Time, sameDayTurnover bool) []*reservationRecord {
+	hr := s.lookup(hotelID)
+	if hr == nil {
+		return nil
+	}
+
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	return hr.conflicting(start, end, sameDayTurnover)
+}
+
+// conflicting returns the records holding rooms that conflict with a stay over the given dates, in start date
+// order; cancelled and no-show reservations are left out. The caller must hold hr.mutex.
+func (hr *hotelReservations) conflicting(start, end time.Time, sameDayTurnover bool) []*reservationRecord {
+	var records []*reservationRecord
+	hr.tree.Conflicting(start, end, sameDayTurnover, func(record *reservationRecord) bool {
+		if models.HoldsRooms(record.reservation.Status) {
+			records = append(records, record)
+		}
+		return true
+	})
+	return records
+}
+
+// roomsLeft returns the number of rooms of a type free on every night from start to the night before end, given
+// the reservations conflicting with those dates and leaving out excludeID. A reservation takes its rooms on the
+// nights a one-night stay would conflict with it (see models.IsConflicting): the nights of its stay, and without
+// same-day turnover the nights before its start and of its end too. The peak of rooms taken is found by sweeping
+// the boundaries of those night ranges in date order, whatever the length of the stay.
+func roomsLeft(roomType models.RoomType, records []*reservationRecord, start, end time.Time, sameDayTurnover bool, excludeID string) int {
+	var changes []roomChange
+	for _, record := range records {
+		if record.reservation.RoomType != roomType.Code || record.reservation.ID == excludeID {
+			continue
+		}
+		from, to := record.start, record.end
+		if !sameDayTurnover {
+			from, to = from.AddDate(0, 0, -1), to.AddDate(0, 0, 1)
+		}
+		if !from.Before(end) || !to.After(start) {
+			continue
+		}
+		if from.Before(start) {
+			from = start
+		}
+		quantity := record.reservation.Quantity
+		changes = append(changes, roomChange{date: from, rooms: quantity}, roomChange{date: to, rooms: -quantity})
+	}
+
+	// Night ranges are half-open, so rooms given back on a night are free for the ranges starting on it
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].date.Equal(changes[j].date) {
+			return changes[i].date.Before(changes[j].date)
+		}
+		return changes[i].rooms < changes[j].rooms
+	})
+	taken, peak := 0, 0
+	for _, change := range changes {
+		if taken += change.rooms; taken > peak {
+			peak = taken
+		}
+	}
+
+	if left := roomType.Count - peak; left > 0 {
+		return left
+	}
+	return 0
+}
+
+// roomChange is a change in the rooms taken by reservations, from the night of date on
+type roomChange struct {
+	date  time.Time
+	rooms int
+}
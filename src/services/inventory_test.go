@@ -0,0 +1,136 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// stayRecord returns a record of a reservation of quantity rooms of a type, from day start to day end of July 2026
+func stayRecord(id, roomType string, quantity, start, end int) *reservationRecord {
+	return &reservationRecord{
+		reservation: models.Reservation{ID: id, RoomType: roomType, Quantity: quantity},
+		start:       day(start),
+		end:         day(end),
+	}
+}
+
+// day returns midnight UTC of a date in July 2026
+func day(d int) time.Time {
+	return time.Date(2026, 7, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRoomsLeft(t *testing.T) {
+	double := models.RoomType{Code: "double", Name: "Double room", Count: 3}
+	tests := []struct {
+		name       string
+		records    []*reservationRecord
+		start, end int
+		excludeID  string
+		turnover   int // rooms left with same-day turnover
+		noTurnover int // rooms left without it
+	}{
+		{
+			name:  "no reservations",
+			start: 1, end: 4,
+			turnover: 3, noTurnover: 3,
+		},
+		{
+			name:    "same stay",
+			records: []*reservationRecord{stayRecord("a", "double", 1, 1, 4)},
+			start:   1, end: 4,
+			turnover: 2, noTurnover: 2,
+		},
+		{
+			name:    "other room type",
+			records: []*reservationRecord{stayRecord("a", "single", 2, 1, 4)},
+			start:   1, end: 4,
+			turnover: 3, noTurnover: 3,
+		},
+		{
+			name:    "ending on the stay's start",
+			records: []*reservationRecord{stayRecord("a", "double", 2, 1, 4)},
+			start:   4, end: 6,
+			turnover: 3, noTurnover: 1,
+		},
+		{
+			name:    "starting on the stay's end",
+			records: []*reservationRecord{stayRecord("a", "double", 2, 6, 8)},
+			start:   4, end: 6,
+			turnover: 3, noTurnover: 1,
+		},
+		{
+			name:    "contained in the stay",
+			records: []*reservationRecord{stayRecord("a", "double", 1, 3, 5)},
+			start:   1, end: 10,
+			turnover: 2, noTurnover: 2,
+		},
+		{
+			name:    "containing the stay",
+			records: []*reservationRecord{stayRecord("a", "double", 2, 1, 10)},
+			start:   3, end: 5,
+			turnover: 1, noTurnover: 1,
+		},
+		{
+			name: "busiest night counts",
+			records: []*reservationRecord{
+				stayRecord("a", "double", 1, 1, 3),
+				stayRecord("b", "double", 1, 2, 4),
+				stayRecord("c", "double", 1, 5, 6),
+			},
+			start: 1, end: 6,
+			turnover: 1, noTurnover: 1,
+		},
+		{
+			name: "back-to-back reservations",
+			records: []*reservationRecord{
+				stayRecord("a", "double", 1, 1, 3),
+				stayRecord("b", "double", 1, 3, 5),
+			},
+			start: 1, end: 5,
+			turnover: 2, noTurnover: 1,
+		},
+		{
+			name:    "one-night stay on a check-out day",
+			records: []*reservationRecord{stayRecord("a", "double", 3, 1, 4)},
+			start:   4, end: 5,
+			turnover: 3, noTurnover: 0,
+		},
+		{
+			name:    "one-night stay on a first night",
+			records: []*reservationRecord{stayRecord("a", "double", 2, 4, 7)},
+			start:   4, end: 5,
+			turnover: 1, noTurnover: 1,
+		},
+		{
+			name:    "overbooked",
+			records: []*reservationRecord{stayRecord("a", "double", 2, 1, 4), stayRecord("b", "double", 2, 1, 4)},
+			start:   1, end: 4,
+			turnover: 0, noTurnover: 0,
+		},
+		{
+			name:    "excluded reservation",
+			records: []*reservationRecord{stayRecord("a", "double", 2, 1, 4), stayRecord("b", "double", 1, 1, 4)},
+			start:   1, end: 4, excludeID: "a",
+			turnover: 2, noTurnover: 2,
+		},
+		{
+			name:    "excluded reservation moving to its check-out day",
+			records: []*reservationRecord{stayRecord("a", "double", 3, 1, 4)},
+			start:   4, end: 6, excludeID: "a",
+			turnover: 3, noTurnover: 3,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end := day(tc.start), day(tc.end)
+			if got := roomsLeft(double, tc.records, start, end, true, tc.excludeID); got != tc.turnover {
+				t.Errorf("roomsLeft with same-day turnover = %d, want %d", got, tc.turnover)
+			}
+			if got := roomsLeft(double, tc.records, start, end, false, tc.excludeID); got != tc.noTurnover {
+				t.Errorf("roomsLeft without same-day turnover = %d, want %d", got, tc.noTurnover)
+			}
+		})
+	}
+}
@@ -1,8 +1,8 @@
 package services
 
 import (
-	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,216 +10,461 @@ import (
 	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
 )
 
-// ReservationService handles reservation operations
+// ReservationService handles reservation operations.
+//
+// Reservations are grouped per hotel, and each hotel has its own lock: inventory
+// checks and the insert/update they guard happen in a single critical section,
+// so concurrent bookings can never take more rooms than a hotel has.
 type ReservationService struct {
 	hotelService *HotelService
-	reservations map[string][]models.Reservation // map[hotelID][]Reservation
-	mutex        sync.RWMutex
+	hotels       map[string]*hotelReservations // map[hotelID]*hotelReservations
+	roomTypes    map[string][]models.RoomType  // configured room types by hotel ID, see SetRoomTypes
+	turnover     map[string]bool               // configured same-day turnover by hotel ID, see SetSameDayTurnover
+	mutex        sync.RWMutex                  // guards the hotels, roomTypes and turnover maps
+}
+
+// hotelReservations holds the reservations of a single hotel, indexed by ID and by date range
+type hotelReservations struct {
+	mutex    sync.Mutex
+	byID     map[string]*reservationRecord
+	tree     intervalTree
+	detached bool // set once removed from the service (hotel deleted or service reset)
+}
+
+// newHotelReservations creates an empty set of hotel reservations
+func newHotelReservations() *hotelReservations {
+	return &hotelReservations{
+		byID: make(map[string]*reservationRecord),
+	}
 }
 
 // NewReservationService creates a new instance of ReservationService
+// and registers its availability checks on the hotel service, for availability searches.
 func NewReservationService(hotelService *HotelService) *ReservationService {
-	return &ReservationService{
+	s := &ReservationService{
 		hotelService: hotelService,
-		reservations: make(map[string][]models.Reservation),
+		hotels:       make(map[string]*hotelReservations),
+		roomTypes:    make(map[string][]models.RoomType),
+		turnover:     make(map[string]bool),
 		mutex:        sync.RWMutex{},
 	}
+	hotelService.SetAvailabilityChecker(s.isFree)
+	return s
 }
 
-// GetReservationsByHotelID returns all reservations for a hotel
+// GetReservationsByHotelID returns all reservations for a hotel, ordered by start date
 func (s *ReservationService) GetReservationsByHotelID(hotelID string) ([]models.Reservation, error) {
 	// Check if the hotel exists
 	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
-		return nil, errors.New("hotel not found")
+		return nil, ErrHotelNotFound
 	}
 
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
 	// Return empty slice if no reservations for this hotel
-	if reservations, ok := s.reservations[hotelID]; ok {
+	reservations := []models.Reservation{}
+	hr := s.lookup(hotelID)
+	if hr == nil {
 		return reservations, nil
 	}
 
-	return []models.Reservation{}, nil
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	hr.tree.Ascend(func(record *reservationRecord) bool {
+		reservations = append(reservations, record.reservation)
+		return true
+	})
+	return reservations, nil
 }
 
-// GetReservationByID returns a reservation by its ID
-func (s *ReservationService) GetReservationByID(hotelID, reservationID string) (*models.Reservation, error) {
+// GetReservationsInRange returns the reservations of a hotel holding a night from the night of from
+// to the night before to, ordered by start date
+func (s *ReservationService) GetReservationsInRange(hotelID, from, to string) ([]models.Reservation, error) {
 	// Check if the hotel exists
 	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
-		return nil, errors.New("hotel not found")
+		return nil, ErrHotelNotFound
 	}
 
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	// Validate dates
+	problems := &ValidationError{}
+	fromDate, err := models.ParseDate(from)
+	if err != nil {
+		problems.Add("from", "must be a date in YYYY-MM-DD format")
+	}
+	toDate, err := models.ParseDate(to)
+	if err != nil {
+		problems.Add("to", "must be a date in YYYY-MM-DD format")
+	}
+	if len(problems.Fields) == 0 && !toDate.After(fromDate) {
+		problems.Add("to", "must be after from")
+	}
+	if err := problems.OrNil(); err != nil {
+		return nil, err
+	}
 
-	// Find the reservation
-	if reservations, ok := s.reservations[hotelID]; ok {
-		for _, reservation := range reservations {
-			if reservation.ID == reservationID {
-				return &reservation, nil
-			}
-		}
+	// Return empty slice if no reservations for this hotel
+	reservations := []models.Reservation{}
+	hr := s.lookup(hotelID)
+	if hr == nil {
+		return reservations, nil
 	}
 
-	return nil, errors.New("reservation not found")
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	hr.tree.Overlapping(fromDate, toDate, func(record *reservationRecord) bool {
+		reservations = append(reservations, record.reservation)
+		return true
+	})
+	return reservations, nil
 }
 
-// CreateReservation creates a new reservation for a hotel
-func (s *ReservationService) CreateReservation(hotelID string, req models.CreateReservationRequest) (*models.Reservation, error) {
+// GetReservationByID returns a reservation by its ID
+func (s *ReservationService) GetReservationByID(hotelID, reservationID string) (*models.Reservation, error) {
 	// Check if the hotel exists
 	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
-		return nil, errors.New("hotel not found")
+		return nil, ErrHotelNotFound
 	}
 
-	// Validate dates
-	startDate, err := models.ParseDate(req.StartDate)
-	if err != nil {
-		return nil, fmt.Errorf("invalid start date: %w", err)
-	}
-
-	endDate, err := models.ParseDate(req.EndDate)
-	if err != nil {
-		return nil, fmt.Errorf("invalid end date: %w", err)
-	}
+	// Find the reservation
+	if hr := s.lookup(hotelID); hr != nil {
+		hr.mutex.Lock()
+		defer hr.mutex.Unlock()
 
-	// Ensure end date is after start date
-	if !endDate.After(startDate) {
-		return nil, errors.New("end date must be after start date")
+		if record, ok := hr.byID[reservationID]; ok {
+			reservation := record.reservation
+			return &reservation, nil
+		}
 	}
 
-	// Check for overlapping reservations
-	if s.hasOverlappingReservations(hotelID, startDate, endDate, "") {
-		return nil, errors.New("reservation dates overlap with an existing booking")
-	}
+	return nil, ErrReservationNotFound
+}
 
-	// Create the reservation
+// CreateReservation creates a new pending reservation for a hotel
+func (s *ReservationService) CreateReservation(hotelID string, req models.CreateReservationRequest) (*models.Reservation, error) {
 	now := time.Now().UTC()
-	reservation := models.Reservation{
+	return s.addReservation(models.Reservation{
 		ID:           uuid.New().String(),
 		HotelID:      hotelID,
 		CustomerName: req.CustomerName,
 		StartDate:    req.StartDate,
 		EndDate:      req.EndDate,
+		RoomType:     req.RoomType,
+		Quantity:     req.Quantity,
 		CreatedAt:    now,
 		UpdatedAt:    now,
+	})
+}
+
+// SeedReservation stores a prepared reservation, e.g. a test fixture, in any status (pending by default).
+// Missing IDs and timestamps are generated, as well as the room type and quantity like CreateReservation does;
+// the same validation as CreateReservation applies.
+func (s *ReservationService) SeedReservation(reservation models.Reservation) (*models.Reservation, error) {
+	if reservation.ID == "" {
+		reservation.ID = uuid.New().String()
+	}
+	if reservation.CreatedAt.IsZero() {
+		reservation.CreatedAt = time.Now().UTC()
 	}
+	if reservation.UpdatedAt.IsZero() {
+		reservation.UpdatedAt = reservation.CreatedAt
+	}
+	return s.addReservation(reservation)
+}
 
-	// Store the reservation
+// Reset removes all reservations
+func (s *ReservationService) Reset() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, ok := s.reservations[hotelID]; !ok {
-		s.reservations[hotelID] = []models.Reservation{}
+	for _, hr := range s.hotels {
+		hr.mutex.Lock()
+		hr.detached = true
+		hr.mutex.Unlock()
+	}
+	s.hotels = make(map[string]*hotelReservations)
+}
+
+// addReservation validates and stores a reservation
+func (s *ReservationService) addReservation(reservation models.Reservation) (*models.Reservation, error) {
+	// Check if the hotel exists, and get its room types
+	roomTypes, err := s.RoomTypes(reservation.HotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate dates and rooms
+	startDate, endDate, err := parseDateRange(reservation.StartDate, reservation.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	roomType, quantity, err := resolveRoomType(roomTypes, reservation.RoomType, reservation.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	reservation.RoomType, reservation.Quantity = roomType.Code, quantity
+	reservation.Nights = models.CountNights(startDate, endDate)
+	if reservation.Status == "" {
+		reservation.Status = models.StatusPending
+	} else if !models.IsReservationStatus(reservation.Status) {
+		return nil, newValidationError("status", fmt.Sprintf("unknown status %q, must be one of: %s",
+			reservation.Status, strings.Join(models.ReservationStatuses, ", ")))
+	}
+
+	// Check there are enough rooms left and store the reservation in one step
+	turnover := s.SameDayTurnover(reservation.HotelID)
+	err = s.withHotel(reservation.HotelID, func(hr *hotelReservations) error {
+		if _, exists := hr.byID[reservation.ID]; exists {
+			return ErrReservationExists
+		}
+		records := hr.conflicting(startDate, endDate, turnover)
+		if models.HoldsRooms(reservation.Status) && roomsLeft(roomType, records, startDate, endDate, turnover, "") < quantity {
+			return ErrDateConflict
+		}
+
+		hr.insert(&reservationRecord{reservation: reservation, start: startDate, end: endDate})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	s.reservations[hotelID] = append(s.reservations[hotelID], reservation)
 
 	return &reservation, nil
 }
 
-// UpdateReservation updates an existing reservation
+// UpdateReservation updates an existing reservation, unless it is closed (checked out, cancelled or no-show).
+// The room type and quantity are kept when the request leaves them out.
 func (s *ReservationService) UpdateReservation(hotelID, reservationID string, req models.UpdateReservationRequest) (*models.Reservation, error) {
-	// Check if the hotel exists
-	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
-		return nil, errors.New("hotel not found")
+	// Check if the hotel exists, and get its room types
+	roomTypes, err := s.RoomTypes(hotelID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate dates
-	startDate, err := models.ParseDate(req.StartDate)
+	startDate, endDate, err := parseDateRange(req.StartDate, req.EndDate)
 	if err != nil {
-		return nil, fmt.Errorf("invalid start date: %w", err)
+		return nil, err
 	}
 
-	endDate, err := models.ParseDate(req.EndDate)
+	// Check there are enough rooms left (besides the current reservation's) and update in one step
+	turnover := s.SameDayTurnover(hotelID)
+	var updated models.Reservation
+	err = s.withHotel(hotelID, func(hr *hotelReservations) error {
+		record, ok := hr.byID[reservationID]
+		if !ok {
+			return ErrReservationNotFound
+		}
+		if models.IsClosed(record.reservation.Status) {
+			return fmt.Errorf("cannot update a %s reservation: %w", record.reservation.Status, ErrReservationClosed)
+		}
+
+		code, quantity := req.RoomType, req.Quantity
+		if code == "" {
+			code = record.reservation.RoomType
+		}
+		if quantity == 0 {
+			quantity = record.reservation.Quantity
+		}
+		roomType, quantity, err := resolveRoomType(roomTypes, code, quantity)
+		if err != nil {
+			return err
+		}
+		records := hr.conflicting(startDate, endDate, turnover)
+		if roomsLeft(roomType, records, startDate, endDate, turnover, reservationID) < quantity {
+			return ErrDateConflict
+		}
+
+		// Update reservation, re-indexing it under its new dates
+		hr.remove(record)
+		updatedRecord := &reservationRecord{reservation: record.reservation, start: startDate, end: endDate}
+		updatedRecord.reservation.CustomerName = req.CustomerName
+		updatedRecord.reservation.StartDate = req.StartDate
+		updatedRecord.reservation.EndDate = req.EndDate
+		updatedRecord.reservation.Nights = models.CountNights(startDate, endDate)
+		updatedRecord.reservation.RoomType = roomType.Code
+		updatedRecord.reservation.Quantity = quantity
+		updatedRecord.reservation.UpdatedAt = time.Now().UTC()
+		hr.insert(updatedRecord)
+
+		updated = updatedRecord.reservation
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("invalid end date: %w", err)
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// TransitionReservation applies an action (see models.Transitions) to a reservation, moving it to its next status.
+// Cancelling a reservation or marking it no-show releases its rooms, but keeps the reservation.
+func (s *ReservationService) TransitionReservation(hotelID, reservationID, action string) (*models.Reservation, error) {
+	if _, ok := models.Transitions[action]; !ok {
+		return nil, newValidationError("action", fmt.Sprintf("unknown action %q", action))
 	}
 
-	// Ensure end date is after start date
-	if !endDate.After(startDate) {
-		return nil, errors.New("end date must be after start date")
+	// Check if the hotel exists
+	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
+		return nil, ErrHotelNotFound
 	}
 
-	// Check for overlapping reservations (excluding the current reservation)
-	if s.hasOverlappingReservations(hotelID, startDate, endDate, reservationID) {
-		return nil, errors.New("reservation dates overlap with an existing booking")
+	hr := s.lookup(hotelID)
+	if hr == nil {
+		return nil, ErrReservationNotFound
 	}
 
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
 
-	// Find and update the reservation
-	if reservations, ok := s.reservations[hotelID]; ok {
-		for i, reservation := range reservations {
-			if reservation.ID == reservationID {
-				// Update reservation
-				reservations[i].CustomerName = req.CustomerName
-				reservations[i].StartDate = req.StartDate
-				reservations[i].EndDate = req.EndDate
-				reservations[i].UpdatedAt = time.Now().UTC()
-				return &reservations[i], nil
-			}
-		}
+	// Find the reservation and move it to its next status
+	record, ok := hr.byID[reservationID]
+	if !ok {
+		return nil, ErrReservationNotFound
 	}
+	status, ok := models.NextStatus(record.reservation.Status, action)
+	if !ok {
+		return nil, fmt.Errorf("cannot %s a %s reservation: %w", action, record.reservation.Status, ErrInvalidTransition)
+	}
+	record.reservation.Status = status
+	record.reservation.UpdatedAt = time.Now().UTC()
 
-	return nil, errors.New("reservation not found")
+	reservation := record.reservation
+	return &reservation, nil
 }
 
-// DeleteReservation deletes a reservation
+// DeleteReservation purges a reservation in any status, leaving no record of it.
+// Guests cancelling go through TransitionReservation instead, which keeps the reservation.
 func (s *ReservationService) DeleteReservation(hotelID, reservationID string) error {
 	// Check if the hotel exists
 	if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
-		return errors.New("hotel not found")
+		return ErrHotelNotFound
 	}
 
+	hr := s.lookup(hotelID)
+	if hr == nil {
+		return ErrReservationNotFound
+	}
+
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	// Find and delete the reservation
+	record, ok := hr.byID[reservationID]
+	if !ok {
+		return ErrReservationNotFound
+	}
+	hr.remove(record)
+	return nil
+}
+
+// DeleteHotel deletes a hotel together with its reservations.
+// Unless cascade is set, hotels that still have reservations are not deleted.
+func (s *ReservationService) DeleteHotel(hotelID string, cascade bool) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// Find and delete the reservation
-	if reservations, ok := s.reservations[hotelID]; ok {
-		for i, reservation := range reservations {
-			if reservation.ID == reservationID {
-				// Remove reservation from slice
-				s.reservations[hotelID] = append(reservations[:i], reservations[i+1:]...)
-				return nil
-			}
+	hr := s.hotels[hotelID]
+	if hr != nil {
+		hr.mutex.Lock()
+		defer hr.mutex.Unlock()
+
+		if len(hr.byID) > 0 && !cascade {
+			return fmt.Errorf("%w, use cascade=true to delete them too", ErrHotelHasReservations)
 		}
 	}
 
-	return errors.New("reservation not found")
+	if err := s.hotelService.DeleteHotel(hotelID); err != nil {
+		return err
+	}
+
+	if hr != nil {
+		hr.detached = true
+		delete(s.hotels, hotelID)
+	}
+	return nil
 }
 
-// hasOverlappingReservations checks if a date range overlaps with any existing reservations
-// excludeReservationID is an optional parameter to exclude a specific reservation from the check (used during updates)
-func (s *ReservationService) hasOverlappingReservations(hotelID string, startDate, endDate time.Time, excludeReservationID string) bool {
+// lookup returns the reservations of a hotel, or nil if it has none yet
+func (s *ReservationService) lookup(hotelID string) *hotelReservations {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	if reservations, ok := s.reservations[hotelID]; ok {
-		for _, reservation := range reservations {
-			// Skip the excluded reservation
-			if reservation.ID == excludeReservationID {
-				continue
-			}
+	return s.hotels[hotelID]
+}
 
-			// Parse existing reservation dates
-			existingStartDate, err := models.ParseDate(reservation.StartDate)
-			if err != nil {
-				continue
-			}
+// withHotel runs fn while holding the lock of the hotel's reservations, creating them if needed.
+// It fails with "hotel not found" if the hotel does not exist (or is deleted concurrently).
+func (s *ReservationService) withHotel(hotelID string, fn func(hr *hotelReservations) error) error {
+	for {
+		// Check if the hotel exists
+		if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
+			return ErrHotelNotFound
+		}
 
-			existingEndDate, err := models.ParseDate(reservation.EndDate)
-			if err != nil {
-				continue
+		hr := s.lookup(hotelID)
+		if hr == nil {
+			s.mutex.Lock()
+			if hr = s.hotels[hotelID]; hr == nil {
+				hr = newHotelReservations()
+				s.hotels[hotelID] = hr
 			}
+			s.mutex.Unlock()
+		}
 
-			// Check for overlap
-			if models.IsOverlapping(startDate, endDate, existingStartDate, existingEndDate) {
-				return true
-			}
+		hr.mutex.Lock()
+		if hr.detached {
+			// Removed concurrently; check the hotel again and retry with a fresh entry
+			hr.mutex.Unlock()
+			continue
+		}
+		if _, err := s.hotelService.GetHotelByID(hotelID); err != nil {
+			// Deleted after the entry was created
+			hr.mutex.Unlock()
+			return ErrHotelNotFound
+		}
+		err := fn(hr)
+		hr.mutex.Unlock()
+		return err
+	}
+}
+
+// parseDateRange parses and validates the start and end dates of a reservation
+func parseDateRange(start, end string) (time.Time, time.Time, error) {
+	problems := &ValidationError{}
+
+	startDate, err := models.ParseDate(start)
+	if err != nil {
+		problems.Add("startDate", "must be a date in YYYY-MM-DD format")
+	}
+
+	endDate, err := models.ParseDate(end)
+	if err != nil {
+		problems.Add("endDate", "must be a date in YYYY-MM-DD format")
+	}
+
+	// Ensure end date is after start date, and the stay is not longer than rooms are counted for
+	if len(problems.Fields) == 0 {
+		if !endDate.After(startDate) {
+			problems.Add("endDate", "must be after startDate")
+		} else if endDate.After(startDate.AddDate(0, 0, MaxAvailabilityNights)) {
+			problems.Add("endDate", fmt.Sprintf("must be at most %d nights after startDate", MaxAvailabilityNights))
 		}
 	}
 
-	return false
-}
\ No newline at end of file
+	if err := problems.OrNil(); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return startDate, endDate, nil
+}
+
+// insert stores a record in the hotel's indexes. The caller must hold hr.mutex.
+func (hr *hotelReservations) insert(record *reservationRecord) {
+	hr.byID[record.reservation.ID] = record
+	hr.tree.Insert(record)
+}
+
+// remove deletes a record from the hotel's indexes. The caller must hold hr.mutex.
+func (hr *hotelReservations) remove(record *reservationRecord) {
+	delete(hr.byID, record.reservation.ID)
+	hr.tree.Delete(record)
+}
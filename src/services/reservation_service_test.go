@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// stressBookings is the number of concurrent bookings of the stress tests, run them with -race
+const stressBookings = 2000
+
+// suiteService returns a reservation service for a single hotel with 4 suites, and the hotel's ID
+func suiteService(t *testing.T) (*ReservationService, string) {
+	t.Helper()
+	hotels := NewHotelService()
+	hotels.SetHotels([]models.Hotel{{ID: "00000000-0000-4000-8000-000000000000", Name: "Test Hotel"}})
+	s := NewReservationService(hotels)
+	hotelID := hotels.GetHotels()[0].ID
+	if err := s.SetRoomTypes(hotelID, []models.RoomType{{Code: "suite", Name: "Suite", Count: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	return s, hotelID
+}
+
+// bookConcurrently creates the reservations all at once, returning the error of each
+func bookConcurrently(s *ReservationService, hotelID string, requests []models.CreateReservationRequest) []error {
+	errs := make([]error, len(requests))
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, errs[i] = s.CreateReservation(hotelID, requests[i])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func TestCreateReservationConcurrentSameDates(t *testing.T) {
+	s, hotelID := suiteService(t)
+
+	requests := make([]models.CreateReservationRequest, stressBookings)
+	for i := range requests {
+		requests[i] = models.CreateReservationRequest{
+			CustomerName: fmt.Sprintf("Guest %d", i),
+			StartDate:    "2026-07-01",
+			EndDate:      "2026-07-04",
+		}
+	}
+
+	succeeded := 0
+	for i, err := range bookConcurrently(s, hotelID, requests) {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, ErrDateConflict):
+			t.Fatalf("booking %d: %v, want %v", i, err, ErrDateConflict)
+		}
+	}
+	if succeeded != 4 {
+		t.Fatalf("%d bookings succeeded, want 4", succeeded)
+	}
+
+	reservations, err := s.GetReservationsByHotelID(hotelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reservations) != 4 {
+		t.Fatalf("%d reservations stored, want 4", len(reservations))
+	}
+	roomTypes, err := s.GetRoomTypes(hotelID, "2026-07-01", "2026-07-04")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left := *roomTypes[0].Remaining; left != 0 {
+		t.Fatalf("%d suites left, want 0", left)
+	}
+}
+
+func TestCreateReservationConcurrentNoOverbooking(t *testing.T) {
+	s, hotelID := suiteService(t)
+	first := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	// Overlapping stays of 1 to 4 nights over two weeks, some for 2 suites at once
+	rng := rand.New(rand.NewSource(3))
+	requests := make([]models.CreateReservationRequest, stressBookings)
+	for i := range requests {
+		start := first.AddDate(0, 0, rng.Intn(14))
+		requests[i] = models.CreateReservationRequest{
+			CustomerName: fmt.Sprintf("Guest %d", i),
+			StartDate:    start.Format(models.DateFormat),
+			EndDate:      start.AddDate(0, 0, 1+rng.Intn(4)).Format(models.DateFormat),
+			Quantity:     1 + rng.Intn(2),
+		}
+	}
+	for i, err := range bookConcurrently(s, hotelID, requests) {
+		if err != nil && !errors.Is(err, ErrDateConflict) {
+			t.Fatalf("booking %d: %v, want %v", i, err, ErrDateConflict)
+		}
+	}
+
+	reservations, err := s.GetReservationsByHotelID(hotelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for night := first; night.Before(first.AddDate(0, 0, 18)); night = night.AddDate(0, 0, 1) {
+		booked := 0
+		for _, reservation := range reservations {
+			start, _ := time.Parse(models.DateFormat, reservation.StartDate)
+			end, _ := time.Parse(models.DateFormat, reservation.EndDate)
+			if models.IsOverlapping(night, night.AddDate(0, 0, 1), start, end) {
+				booked += reservation.Quantity
+			}
+		}
+		if booked > 4 {
+			t.Fatalf("%s: %d suites booked, want at most 4", night.Format(models.DateFormat), booked)
+		}
+	}
+}
+
+func TestAvailabilitySearchRoomType(t *testing.T) {
+	hotels := NewHotelService()
+	hotels.SetHotels(testHotels(2))
+	s := NewReservationService(hotels)
+	hotelID := hotels.GetHotels()[0].ID
+	roomTypes := []models.RoomType{{Code: "double", Name: "Double room", Count: 1}, {Code: "suite", Name: "Suite", Count: 1}}
+	if err := s.SetRoomTypes(hotelID, roomTypes); err != nil {
+		t.Fatal(err)
+	}
+
+	// A booking without room type takes the first one, the double room
+	if _, err := s.CreateReservation(hotelID, models.CreateReservationRequest{
+		CustomerName: "Guest", StartDate: "2026-07-01", EndDate: "2026-07-04",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		roomType string
+		want     bool
+	}{
+		{"", false},
+		{"double", false},
+		{"suite", true},
+		{"family_suite", false},
+	}
+	for _, tc := range tests {
+		page := hotels.SearchHotels(models.SearchParams{CheckIn: "2026-07-02", CheckOut: "2026-07-03", RoomType: tc.roomType})
+		found := false
+		for _, result := range page.Hotels {
+			found = found || result.ID == hotelID
+		}
+		if found != tc.want {
+			t.Errorf("roomType %q: hotel found = %v, want %v", tc.roomType, found, tc.want)
+		}
+	}
+}
+
+func TestAvailabilityBlockedNights(t *testing.T) {
+	tests := []struct {
+		sameDayTurnover bool
+		want            []string // statuses from June 30 to July 5, around a stay from July 1 to 4
+	}{
+		{true, []string{models.NightAvailable, models.NightBooked, models.NightBooked, models.NightBooked, models.NightAvailable}},
+		{false, []string{models.NightBlocked, models.NightBooked, models.NightBooked, models.NightBooked, models.NightBlocked}},
+	}
+	for _, tc := range tests {
+		s, hotelID := suiteService(t)
+		if err := s.SetSameDayTurnover(hotelID, tc.sameDayTurnover); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.CreateReservation(hotelID, models.CreateReservationRequest{
+			CustomerName: "Group", StartDate: "2026-07-01", EndDate: "2026-07-04", Quantity: 4,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		nights, err := s.GetAvailability(hotelID, "2026-06-30", "2026-07-05", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, night := range nights {
+			if night.Status != tc.want[i] {
+				t.Errorf("same-day turnover %v: %s is %s, want %s", tc.sameDayTurnover, night.Date, night.Status, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestCreateReservationStayLength(t *testing.T) {
+	tests := []struct {
+		start, end string
+		valid      bool
+	}{
+		{"2026-07-01", "2027-07-01", true}, // 365 nights
+		{"2028-01-01", "2029-01-01", true}, // 366 nights, a leap year
+		{"2026-07-01", "2027-07-03", false},
+		{"0001-01-01", "9999-12-31", false},
+	}
+	for _, tc := range tests {
+		s, hotelID := suiteService(t)
+		_, err := s.CreateReservation(hotelID, models.CreateReservationRequest{
+			CustomerName: "Long Stay", StartDate: tc.start, EndDate: tc.end,
+		})
+		var invalid *ValidationError
+		switch {
+		case tc.valid && err != nil:
+			t.Errorf("stay from %s to %s: %v", tc.start, tc.end, err)
+		case !tc.valid && (!errors.As(err, &invalid) || invalid.Fields[0].Field != "endDate"):
+			t.Errorf("stay from %s to %s: error = %v, want an endDate validation error", tc.start, tc.end, err)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// landmarkPrefixes are the leading words stripped from LocationDescription to get a landmark, e.g. "Near Louvre Museum"
+var landmarkPrefixes = []string{"near ", "on ", "in "}
+
+// suggestIndex is a prefix trie over the words of the hotel names, cities and landmarks.
+// It is copy-on-write like the snapshots holding it: update only copies the entries and
+// the trie paths of the changed hotel's words.
+type suggestIndex struct {
+	entries *cowMap[int, suggestEntry] // entry ID -> entry
+	ids     *cowMap[string, int]       // suggestion key -> entry ID, see suggestKey
+	nextID  int                        // ID of the next new entry; IDs are never reused
+	root    *trieNode
+}
+
+// suggestEntry is a completion that can be suggested
+type suggestEntry struct {
+	suggestion models.Suggestion
+	first      string // first folded word of the text
+	words      int    // number of words in the text
+	hotels     int    // number of hotels having the suggestion; cities and landmarks are shared
+}
+
+// trieNode is a node of the suggestion trie, keyed by folded runes.
+// Nodes are never modified once published, except for appending to entries (see withPosition).
+type trieNode struct {
+	children map[rune]*trieNode
+	entries  []int // entries having the word ending at this node, ascending
+}
+
+// newSuggestIndex builds the suggestion index for the given hotels, skipping deleted (nil) ones.
+// Cities and landmarks shared by several hotels are suggested once.
+func newSuggestIndex(hotels []*models.Hotel) *suggestIndex {
+	w := (&suggestIndex{
+		entries: newCowMap[int, suggestEntry](hashInt),
+		ids:     newCowMap[string, int](hashString),
+		root:    &trieNode{},
+	}).writer()
+
+	// Hotels first, then cities and landmarks, so entries rank in that order on ties
+	for _, kind := range []string{models.SuggestionHotel, models.SuggestionCity, models.SuggestionLandmark} {
+		for _, hotel := range hotels {
+			if hotel == nil {
+				continue
+			}
+			for _, suggestion := range hotelSuggestions(hotel) {
+				if suggestion.Type == kind {
+					w.add(suggestion)
+				}
+			}
+		}
+	}
+	return w.done()
+}
+
+// update returns a copy of the index where a hotel changes from old to hotel.
+// Either can be nil, for hotels being added or deleted.
+func (index *suggestIndex) update(old, hotel *models.Hotel) *suggestIndex {
+	w := index.writer()
+	if old != nil {
+		for _, suggestion := range hotelSuggestions(old) {
+			w.remove(suggestion)
+		}
+	}
+	if hotel != nil {
+		for _, suggestion := range hotelSuggestions(hotel) {
+			w.add(suggestion)
+		}
+	}
+	return w.done()
+}
+
+// hotelSuggestions returns the suggestions of a hotel: its name, city and landmark
+func hotelSuggestions(hotel *models.Hotel) []models.Suggestion {
+	return []models.Suggestion{
+		{Type: models.SuggestionHotel, Text: hotel.Name, HotelID: hotel.ID},
+		{Type: models.SuggestionCity, Text: hotel.City},
+		{Type: models.SuggestionLandmark, Text: landmark(hotel.LocationDescription)},
+	}
+}
+
+// suggestKey identifies a suggestion: hotels by ID, cities and landmarks by their folded text
+func suggestKey(suggestion models.Suggestion) string {
+	if suggestion.Type == models.SuggestionHotel {
+		return suggestion.Type + ":" + suggestion.HotelID
+	}
+	return suggestion.Type + ":" + foldText(suggestion.Text)
+}
+
+// hashInt spreads integer keys over the shards of a cowMap
+func hashInt(key int) uint32 {
+	return uint32(key) * 2654435761
+}
+
+// suggestWriter builds a modified copy of a suggestIndex
+type suggestWriter struct {
+	index   *suggestIndex
+	entries *cowMapWriter[int, suggestEntry]
+	ids     *cowMapWriter[string, int]
+	owned   map[*trieNode]bool // trie nodes copied by this writer, which it can modify
+}
+
+// writer returns a writer building a modified copy of the index
+func (index *suggestIndex) writer() *suggestWriter {
+	w := &suggestWriter{
+		index:   &suggestIndex{nextID: index.nextID},
+		entries: index.entries.writer(),
+		ids:     index.ids.writer(),
+		owned:   map[*trieNode]bool{},
+	}
+	w.index.root = w.copyNode(index.root)
+	return w
+}
+
+// done returns the new index. The writer must not be used afterwards.
+func (w *suggestWriter) done() *suggestIndex {
+	w.index.entries, w.index.ids = w.entries.done(), w.ids.done()
+	return w.index
+}
+
+// add adds a hotel's suggestion, or counts one more hotel for a suggestion already indexed
+func (w *suggestWriter) add(suggestion models.Suggestion) {
+	key := suggestKey(suggestion)
+	if id, ok := w.ids.get(key); ok {
+		entry, _ := w.entries.get(id)
+		entry.hotels++
+		w.entries.set(id, entry)
+		return
+	}
+
+	words := suggestWords(suggestion.Text)
+	if len(words) == 0 {
+		return
+	}
+	id := w.index.nextID
+	w.index.nextID++
+	w.ids.set(key, id)
+	w.entries.set(id, suggestEntry{suggestion: suggestion, first: words[0], words: len(words), hotels: 1})
+
+	for _, word := range uniqueWords(words) {
+		node := w.index.root
+		for _, r := range word {
+			node = w.child(node, r)
+		}
+		// IDs only grow, so appending keeps the entries ascending
+		node.entries = append(node.entries, id)
+	}
+}
+
+// remove counts one hotel less for a suggestion, removing it once no hotel has it
+func (w *suggestWriter) remove(suggestion models.Suggestion) {
+	key := suggestKey(suggestion)
+	id, ok := w.ids.get(key)
+	if !ok {
+		return
+	}
+	entry, _ := w.entries.get(id)
+	if entry.hotels--; entry.hotels > 0 {
+		w.entries.set(id, entry)
+		return
+	}
+	w.ids.delete(key)
+	w.entries.delete(id)
+
+	for _, word := range uniqueWords(suggestWords(suggestion.Text)) {
+		runes := []rune(word)
+		path := []*trieNode{w.index.root}
+		for _, r := range runes {
+			path = append(path, w.child(path[len(path)-1], r))
+		}
+		node := path[len(runes)]
+		node.entries = withoutPosition(node.entries, id)
+
+		// Prune the nodes left without entries or children
+		for k := len(runes); k > 0 && len(path[k].entries) == 0 && len(path[k].children) == 0; k-- {
+			delete(path[k-1].children, runes[k-1])
+		}
+	}
+}
+
+// child returns the child of an owned node for a rune, copied (or created) so the writer owns it too
+func (w *suggestWriter) child(parent *trieNode, r rune) *trieNode {
+	child, ok := parent.children[r]
+	if ok && w.owned[child] {
+		return child
+	}
+	if child = w.copyNode(child); parent.children == nil {
+		parent.children = map[rune]*trieNode{}
+	}
+	parent.children[r] = child
+	return child
+}
+
+// copyNode returns an owned copy of a node, or a new node if it is nil.
+// The copy shares the node's entries, which the writer only appends to or replaces.
+func (w *suggestWriter) copyNode(node *trieNode) *trieNode {
+	copied := &trieNode{}
+	if node != nil {
+		copied.entries = node.entries
+		if len(node.children) > 0 {
+			copied.children = make(map[rune]*trieNode, len(node.children))
+			for r, child := range node.children {
+				copied.children[r] = child
+			}
+		}
+	}
+	w.owned[copied] = true
+	return copied
+}
+
+// uniqueWords returns words without their repetitions, in order
+func uniqueWords(words []string) []string {
+	seen := map[string]bool{}
+	unique := words[:0]
+	for _, word := range words {
+		if !seen[word] {
+			seen[word] = true
+			unique = append(unique, word)
+		}
+	}
+	return unique
+}
+
+// landmark extracts the landmark of a location description, e.g. "Near Louvre Museum" -> "Louvre Museum"
+func landmark(description string) string {
+	description = strings.TrimSpace(description)
+	lower := strings.ToLower(description)
+	for _, prefix := range landmarkPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return strings.TrimSpace(description[len(prefix):])
+		}
+	}
+	return description
+}
+
+// suggestWords splits text into folded words, keeping stop words and endings
+func suggestWords(text string) []string {
+	return strings.FieldsFunc(foldText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxEdits is the number of typos tolerated in a query word of the given length
+func maxEdits(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 6:
+		return 1
+	}
+	return 2
+}
+
+// suggest returns up to limit completions for a partial query.
+// Every query word must match a word of the suggestion, the last one as a prefix, within a few typos.
+// Suggestions are ranked by typos, then by how early they match, by type and by length.
+func (index *suggestIndex) suggest(query string, limit int) []models.Suggestion {
+	words := suggestWords(query)
+	if len(words) == 0 {
+		return []models.Suggestion{}
+	}
+
+	// edits holds the typos of each candidate entry, summed over the query words
+	var edits map[int]int
+	for i, word := range words {
+		matched := index.fuzzy([]rune(word), i == len(words)-1)
+		if i == 0 {
+			edits = matched
+			continue
+		}
+		for id, total := range edits {
+			if distance, ok := matched[id]; ok {
+				edits[id] = total + distance
+			} else {
+				delete(edits, id)
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(edits))
+	entries := make(map[int]suggestEntry, len(edits))
+	first := make(map[int]bool, len(edits)) // entries whose first word matches the first query word
+	for id := range edits {
+		ids = append(ids, id)
+		entries[id], _ = index.entries.get(id)
+		first[id] = strings.HasPrefix(entries[id].first, words[0])
+	}
+	sort.Slice(ids, func(a, b int) bool {
+		x, y := ids[a], ids[b]
+		if edits[x] != edits[y] {
+			return edits[x] < edits[y]
+		}
+		if first[x] != first[y] {
+			return first[x]
+		}
+		ex, ey := entries[x], entries[y]
+		if ex.suggestion.Type != ey.suggestion.Type {
+			return suggestionRank(ex.suggestion.Type) < suggestionRank(ey.suggestion.Type)
+		}
+		if ex.words != ey.words {
+			return ex.words < ey.words
+		}
+		if ex.suggestion.Text != ey.suggestion.Text {
+			return ex.suggestion.Text < ey.suggestion.Text
+		}
+		return x < y
+	})
+
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	suggestions := make([]models.Suggestion, len(ids))
+	for i, id := range ids {
+		suggestions[i] = entries[id].suggestion
+	}
+	return suggestions
+}
+
+// suggestionRank orders suggestion types: hotels first, then cities and landmarks
+func suggestionRank(suggestionType string) int {
+	switch suggestionType {
+	case models.SuggestionHotel:
+		return 0
+	case models.SuggestionCity:
+		return 1
+	}
+	return 2
+}
+
+// fuzzy returns the entries having a word within maxEdits typos of the query word, with the fewest typos found.
+// With prefix, the query word may match the start of a longer word.
+// The trie is walked computing one row of the optimal string alignment distance
+// (Levenshtein with transpositions) per node, and pruned once a row exceeds maxEdits.
+func (index *suggestIndex) fuzzy(word []rune, prefix bool) map[int]int {
+	limit := maxEdits(len(word))
+	matched := map[int]int{}
+
+	record := func(node *trieNode, distance int) {
+		for _, id := range node.entries {
+			if best, ok := matched[id]; !ok || distance < best {
+				matched[id] = distance
+			}
+		}
+	}
+
+	// walk visits node, reached through r, given the rows of its parent and grandparent.
+	// best is the smallest prefix distance found on the path so far.
+	var walk func(node *trieNode, r, parentRune rune, parentRow, grandRow []int, best int)
+	walk = func(node *trieNode, r, parentRune rune, parentRow, grandRow []int, best int) {
+		row := make([]int, len(word)+1)
+		row[0] = parentRow[0] + 1
+		smallest := row[0]
+		for i := 1; i <= len(word); i++ {
+			cost := 1
+			if word[i-1] == r {
+				cost = 0
+			}
+			row[i] = min3(row[i-1]+1, parentRow[i]+1, parentRow[i-1]+cost)
+			if grandRow != nil && i > 1 && word[i-1] == parentRune && word[i-2] == r && grandRow[i-2]+1 < row[i] {
+				row[i] = grandRow[i-2] + 1
+			}
+			if row[i] < smallest {
+				smallest = row[i]
+			}
+		}
+
+		distance := row[len(word)]
+		if prefix && distance < best {
+			best = distance
+		}
+		switch {
+		case prefix && best <= limit:
+			record(node, best)
+		case !prefix && distance <= limit:
+			record(node, distance)
+		}
+
+		if smallest > limit && !(prefix && best <= limit) {
+			return
+		}
+		for child, next := range node.children {
+			walk(next, child, r, row, parentRow, best)
+		}
+	}
+
+	first := make([]int, len(word)+1)
+	for i := range first {
+		first[i] = i
+	}
+	for r, child := range index.root.children {
+		walk(child, r, 0, first, nil, limit+1)
+	}
+	return matched
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// Suggest returns autocomplete suggestions (hotel names, cities and landmarks) for a partial query
+func (s *HotelService) Suggest(query string, limit int) []models.Suggestion {
+	return s.snapshot.Load().suggest.suggest(query, limit)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/vandimit/simple-hotels-mock-rest-api/src/models"
+)
+
+// BM25 parameters: term frequency saturation and document length normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// textFields are the hotel fields searched by full-text queries, with the weight of their terms
+var textFields = []struct {
+	weight float64
+	value  func(hotel *models.Hotel) string
+}{
+	{3, func(hotel *models.Hotel) string { return hotel.Name }},
+	{2, func(hotel *models.Hotel) string { return hotel.City }},
+	{1.5, func(hotel *models.Hotel) string { return hotel.LocationDescription }},
+	{1, func(hotel *models.Hotel) string { return hotel.Address1 }},
+	{1, func(hotel *models.Hotel) string { return hotel.ShortDescription }},
+}
+
+// stopWords are left out of the index and of queries
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true, "or": true,
+	"the": true, "to": true, "will": true, "with": true, "you": true, "your": true,
+}
+
+// accentFolds maps accented lower-case letters to their unaccented spelling
+var accentFolds = map[rune]string{}
+
+func init() {
+	for plain, accented := range map[string]string{
+		"a": "àáâãäåāăą", "c": "çćĉċč", "d": "ďđð", "e": "èéêëēĕėęě", "g": "ĝğġģ", "h": "ĥħ",
+		"i": "ìíîïĩīĭįı", "j": "ĵ", "k": "ķ", "l": "ĺļľŀł", "n": "ñńņňŉ", "o": "òóôõöøōŏő",
+		"r": "ŕŗř", "s": "śŝşš", "t": "ţťŧ", "u": "ùúûüũūŭůűų", "w": "ŵ", "y": "ýÿŷ", "z": "źżž",
+		"ss": "ß", "ae": "æ", "oe": "œ", "th": "þ",
+	} {
+		for _, r := range accented {
+			accentFolds[r] = plain
+		}
+	}
+}
+
+// foldText lower-cases text and removes accents, e.g. "Café Zürich" -> "cafe zurich"
+func foldText(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range strings.ToLower(text) {
+		if folded, ok := accentFolds[r]; ok {
+			b.WriteString(folded)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// tokenize splits text into folded, stemmed terms, dropping stop words
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(foldText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, word := range words {
+		if !stopWords[word] {
+			terms = append(terms, stem(word))
+		}
+	}
+	return terms
+}
+
+// stem applies a light English stemming, mostly removing plurals and common verb endings
+func stem(word string) string {
+	switch n := len(word); {
+	case n > 4 && strings.HasSuffix(word, "ies"):
+		return word[:n-3] + "y"
+	case n > 4 && strings.HasSuffix(word, "sses"):
+		return word[:n-2]
+	case n > 5 && strings.HasSuffix(word, "ing"):
+		return word[:n-3]
+	case n > 4 && strings.HasSuffix(word, "ed") && !strings.HasSuffix(word, "eed"):
+		return word[:n-2]
+	case n > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return word[:n-1]
+	}
+	return word
+}
+
+// textIndex is an inverted index over the hotel text fields, scored with BM25.
+// It is copy-on-write like the snapshots holding it: update copies the posting lists of the
+// changed hotel's terms, and keeps the corpus statistics as running totals.
+type textIndex struct {
+	postings    *cowMap[string, []posting] // term -> hotels containing it, by ascending position
+	documents   int                        // number of indexed hotels
+	totalLength float64                    // weighted number of terms of all the indexed hotels
+}
+
+// posting records the weighted frequency of a term in a hotel
+type posting struct {
+	position  int
+	frequency float64
+	length    float64 // weighted number of terms of the hotel
+}
+
+// newTextIndex indexes the text fields of the given hotels, skipping deleted (nil) ones
+func newTextIndex(hotels []*models.Hotel) *textIndex {
+	index := &textIndex{}
+	postings := newCowMap[string, []posting](hashString).writer()
+	for i, hotel := range hotels {
+		if hotel == nil {
+			continue
+		}
+		frequencies, length := termFrequencies(hotel)
+		for term, frequency := range frequencies {
+			list, _ := postings.get(term)
+			postings.set(term, append(list, posting{position: i, frequency: frequency, length: length}))
+		}
+		index.documents++
+		index.totalLength += length
+	}
+	index.postings = postings.done()
+	return index
+}
+
+// update returns a copy of the index where the hotel at position i changes from old to hotel.
+// Either can be nil, for hotels being added or deleted.
+func (index *textIndex) update(i int, old, hotel *models.Hotel) *textIndex {
+	next := &textIndex{documents: index.documents, totalLength: index.totalLength}
+	postings := index.postings.writer()
+	if old != nil {
+		frequencies, length := termFrequencies(old)
+		for term := range frequencies {
+			list, _ := postings.get(term)
+			if list = withoutPosting(list, i); len(list) == 0 {
+				postings.delete(term)
+			} else {
+				postings.set(term, list)
+			}
+		}
+		next.documents--
+		next.totalLength -= length
+	}
+	if hotel != nil {
+		frequencies, length := termFrequencies(hotel)
+		for term, frequency := range frequencies {
+			list, _ := postings.get(term)
+			postings.set(term, withPosting(list, posting{position: i, frequency: frequency, length: length}))
+		}
+		next.documents++
+		next.totalLength += length
+	}
+	next.postings = postings.done()
+	return next
+}
+
+// termFrequencies returns the weighted frequency of each term of a hotel's text fields, and their weighted number
+func termFrequencies(hotel *models.Hotel) (map[string]float64, float64) {
+	frequencies := map[string]float64{}
+	length := 0.0
+	for _, field := range textFields {
+		for _, term := range tokenize(field.value(hotel)) {
+			frequencies[term] += field.weight
+			length += field.weight
+		}
+	}
+	return frequencies, length
+}
+
+// withPosting returns a posting list with p added at its position, leaving list unchanged.
+// Appending past the last position reuses the spare capacity of the backing array (see hotelSnapshot).
+func withPosting(list []posting, p posting) []posting {
+	if n := len(list); n == 0 || list[n-1].position < p.position {
+		return append(list, p)
+	}
+	k := searchPosting(list, p.position)
+	result := make([]posting, 0, len(list)+1)
+	result = append(result, list[:k]...)
+	result = append(result, p)
+	return append(result, list[k:]...)
+}
+
+// withoutPosting returns a posting list without the posting of a position, leaving list unchanged
+func withoutPosting(list []posting, position int) []posting {
+	k := searchPosting(list, position)
+	if k == len(list) || list[k].position != position {
+		return list
+	}
+	result := make([]posting, 0, len(list)-1)
+	result = append(result, list[:k]...)
+	return append(result, list[k+1:]...)
+}
+
+// searchPosting returns the index of the first posting of a list not before a position
+func searchPosting(list []posting, position int) int {
+	return sort.Search(len(list), func(k int) bool {
+		return list[k].position >= position
+	})
+}
+
+// search returns the BM25 score of every hotel containing all the query terms, by position, and the corpus
+// statistics of the scores: the given ones (from a relevance cursor) if any, else the current ones.
+func (index *textIndex) search(query string, corpus *models.TextCorpus) (map[int]float64, *models.TextCorpus) {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return map[int]float64{}, corpus
+	}
+
+	// Start from the rarest term, so the intersection shrinks quickly
+	var lists [][]posting
+	var listTerms []string
+	current := &models.TextCorpus{Documents: index.documents, Terms: map[string]int{}}
+	for _, term := range terms {
+		if _, seen := current.Terms[term]; seen {
+			continue
+		}
+		list, ok := index.postings.get(term)
+		if !ok {
+			return map[int]float64{}, corpus
+		}
+		lists = append(lists, list)
+		listTerms = append(listTerms, term)
+		current.Terms[term] = len(list)
+	}
+	if corpus == nil {
+		current.AvgLength = index.totalLength / float64(index.documents)
+		corpus = current
+	}
+	rarest := 0
+	for i, list := range lists {
+		if len(list) < len(lists[rarest]) {
+			rarest = i
+		}
+	}
+
+	scores := make(map[int]float64, len(lists[rarest]))
+	for _, p := range lists[rarest] {
+		scores[p.position] = 0
+	}
+
+	n, avgLength := float64(corpus.Documents), corpus.AvgLength
+	for k, list := range lists {
+		documents, ok := corpus.Terms[listTerms[k]]
+		if !ok {
+			documents = len(list)
+		}
+		idf := math.Log(1 + (n-float64(documents)+0.5)/(float64(documents)+0.5))
+		matched := make(map[int]float64, len(scores))
+		for _, p := range list {
+			score, ok := scores[p.position]
+			if !ok {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*p.length/avgLength)
+			matched[p.position] = score + idf*p.frequency*(bm25K1+1)/(p.frequency+norm)
+		}
+		scores = matched
+	}
+	return scores, corpus
+}
@@ -1,9 +1,13 @@
 package utils
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/gorilla/mux"
 )
 
 // LoggingMiddleware logs information about each request
@@ -25,21 +29,88 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// CORSMiddleware adds CORS headers to responses
-func CORSMiddleware(next http.Handler) http.Handler {
+// JSONLoggingMiddleware logs information about each request as a single JSON line
+func JSONLoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+		start := time.Now()
 
-		// Call the next handler
-		next.ServeHTTP(w, r)
+		// Call the next handler, capturing the status code
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		// Log request details
+		line, _ := json.Marshal(map[string]interface{}{
+			"time":       start.UTC().Format(time.RFC3339Nano),
+			"method":     r.Method,
+			"uri":        r.RequestURI,
+			"remoteAddr": r.RemoteAddr,
+			"status":     recorder.status,
+			"durationMs": float64(time.Since(start).Microseconds()) / 1000,
+		})
+		log.Writer().Write(append(line, '\n'))
 	})
-}
\ No newline at end of file
+}
+
+// NewLoggingMiddleware returns the logging middleware for the given format ("text" or "json")
+func NewLoggingMiddleware(format string) mux.MiddlewareFunc {
+	if format == "json" {
+		return JSONLoggingMiddleware
+	}
+	return LoggingMiddleware
+}
+
+// exposeHeaders lists the response headers browsers may read besides the CORS-safelisted ones (pagination)
+const exposeHeaders = "Link, X-Total-Count"
+
+// NewCORSMiddleware returns a CORS middleware for the given allowed origins, methods and headers.
+// An origin of "*" allows any origin; otherwise the request Origin is echoed back when it is allowed.
+func NewCORSMiddleware(origins, methods, headers []string) mux.MiddlewareFunc {
+	allowAny := false
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[origin] = true
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Set CORS headers
+			if allowAny {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Add("Vary", "Origin")
+				if origin := r.Header.Get("Origin"); allowed[origin] {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+
+			// Handle preflight requests
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			// Call the next handler
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// statusRecorder is an http.ResponseWriter that remembers the status code written
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}